@@ -11,6 +11,7 @@ import (
 
 	"github.com/Alwanly/Houses-Prices/worker/internal/api"
 	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/geo"
 	"github.com/Alwanly/Houses-Prices/worker/internal/notification"
 	"github.com/Alwanly/Houses-Prices/worker/internal/pkg/logger"
 	"github.com/Alwanly/Houses-Prices/worker/internal/scheduler"
@@ -20,8 +21,13 @@ import (
 	"go.uber.org/zap"
 )
 
+// version is the build version published in the worker registry, set with
+// -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
 	cfgPath := flag.String("config", "./configs/config.yaml", "path to config file")
+	backfill := flag.Bool("backfill-addresses", false, "resolve the address of stored listings that have none, then exit")
 	flag.Parse()
 
 	// Load config
@@ -31,6 +37,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := site.ValidateConfig(cfg.Sites); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Setup logger
 	log, err := logger.New(cfg.Logging.Level, cfg.Logging.Format)
 	if err != nil {
@@ -55,35 +66,48 @@ func main() {
 	}
 	defer redisWrap.Close()
 
-	// Repository
+	// Repositories
 	repo := storage.NewListingRepository(mongoDB.Database())
+	runs := storage.NewRunRepository(mongoDB.Database())
+
+	if *backfill {
+		backfillAddresses(ctx, repo, log)
+		return
+	}
 
 	// Notifier
 	note := notification.NewNotifier(redisWrap.Client(), log)
 
+	workerID := hostnameOrPID()
+
 	// Service
-	svc := service.NewScraperService(repo, note, log)
+	svc := service.NewScraperService(repo, runs, note, workerID, log)
 
-	// Register site-specific scrapers
-	for _, s := range cfg.Sites {
+	// Register scrapers from the site registry
+	for i := range cfg.Sites {
+		s := &cfg.Sites[i]
 		if !s.Enabled {
 			continue
 		}
-		switch s.Name {
-		case "rumah123":
-			r := site.NewRumah123Scraper(&s, log)
-			svc.RegisterScraper(s.Name, r)
-		default:
-			log.Warn("no scraper for site", zap.String("site", s.Name))
+		scraper, err := site.New(s, log)
+		if err != nil {
+			log.Fatal("scraper init failed", zap.String("site", s.Name), zap.Error(err))
 		}
+		svc.RegisterSite(s, scraper)
 	}
 
 	// Scheduler
-	sched := scheduler.New(svc, redisWrap.Client(), hostnameOrPID(), log)
+	sched := scheduler.New(svc, redisWrap.Client(), workerID, cfg.Scheduler, log)
+	sched.SetVersion(version)
 	for _, s := range cfg.Sites {
 		if !s.Enabled {
 			continue
 		}
+		opts, err := scheduler.SiteOptionsFromConfig(&s, cfg.Scheduler)
+		if err != nil {
+			log.Fatal("invalid site schedule options", zap.String("site", s.Name), zap.Error(err))
+		}
+		sched.SetSiteOptions(s.Name, opts)
 		if err := sched.AddJob(s.Name, s.Schedule, s.BaseURL); err != nil {
 			log.Warn("failed to add job", zap.String("site", s.Name), zap.Error(err))
 		}
@@ -92,7 +116,7 @@ func main() {
 	sched.Start()
 
 	// API server
-	apiSrv := api.NewServer(&cfg.Server, svc, note, log)
+	apiSrv := api.NewServer(&cfg.Server, svc, sched, sched, sched, log)
 	if err := apiSrv.Start(); err != nil {
 		log.Fatal("failed to start api server", zap.Error(err))
 	}
@@ -123,6 +147,17 @@ func main() {
 	log.Info("shutdown complete")
 }
 
+// backfillAddresses resolves the address of stored listings saved without one
+func backfillAddresses(ctx context.Context, repo storage.ListingRepository, log *zap.Logger) {
+	res, err := repo.BackfillAddresses(ctx, geo.Default().Normalize)
+	if err != nil {
+		log.Fatal("address backfill failed", zap.Error(err))
+	}
+	log.Info("address backfill done",
+		zap.Int64("updated", res.Updated),
+		zap.Int64("unresolved", res.Unresolved))
+}
+
 func hostnameOrPID() string {
 	hn, err := os.Hostname()
 	if err == nil && hn != "" {
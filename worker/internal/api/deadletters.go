@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/scheduler"
+)
+
+type deadLetterListResponse struct {
+	Items []scheduler.DeadLetter `json:"items"`
+}
+
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := s.queue.ListDeadLetters(r.Context())
+	if err != nil {
+		s.logger.Error("list dead letters failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch dead letters")
+		return
+	}
+	writeJSON(w, http.StatusOK, deadLetterListResponse{Items: letters})
+}
+
+func (s *Server) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	job, err := s.queue.ReplayDeadLetter(r.Context(), id)
+	switch {
+	case errors.Is(err, scheduler.ErrDeadLetterNotFound):
+		writeError(w, http.StatusNotFound, codeNotFound, "dead letter not found")
+		return
+	case errors.Is(err, scheduler.ErrAlreadyQueued) || errors.Is(err, scheduler.ErrQuietHours):
+		writeError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case err != nil:
+		s.logger.Error("replay dead letter failed", zap.String("job_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to replay dead letter")
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.JobID)
+	writeJSON(w, http.StatusAccepted, triggerResponse{Status: job.Status, JobID: job.JobID})
+}
+
+func (s *Server) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	err := s.queue.DeleteDeadLetter(r.Context(), id)
+	if errors.Is(err, scheduler.ErrDeadLetterNotFound) {
+		writeError(w, http.StatusNotFound, codeNotFound, "dead letter not found")
+		return
+	}
+	if err != nil {
+		s.logger.Error("delete dead letter failed", zap.String("job_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to delete dead letter")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,53 +1,267 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/scheduler"
+	"github.com/Alwanly/Houses-Prices/worker/internal/service"
 	"go.uber.org/zap"
 )
 
 type listResponse struct {
-	Items []*model.Listing `json:"items"`
+	Items      []*model.Listing `json:"items"`
+	Total      int64            `json:"total"`
+	Page       int              `json:"page"`
+	Limit      int              `json:"limit"`
+	TotalPages int64            `json:"total_pages"`
+	Next       string           `json:"next,omitempty"`
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Leader string `json:"leader,omitempty"`
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	resp := healthResponse{Status: "ok"}
+
+	leader, err := s.workers.Leader(r.Context())
+	if err != nil {
+		s.logger.Warn("get leader failed", zap.Error(err))
+	}
+	resp.Leader = leader
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type triggerResponse struct {
+	Status string `json:"status"`
+	JobID  string `json:"job_id"`
+}
+
+type jobListResponse struct {
+	Items []*model.JobStatus `json:"items"`
+	Total int64              `json:"total"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+}
+
+type priceHistoryResponse struct {
+	ListingID string               `json:"listing_id"`
+	Items     []*model.PriceChange `json:"items"`
 }
 
 func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	listings, err := s.svc.GetListings(r.Context(), nil)
+	lq, apiErr := parseListingQuery(r.URL.Query())
+	if apiErr != nil {
+		writeInvalidParam(w, apiErr)
+		return
+	}
+
+	listings, err := s.svc.GetListings(r.Context(), lq.filter)
 	if err != nil {
 		s.logger.Error("get listings failed", zap.Error(err))
-		http.Error(w, "failed to fetch listings", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch listings")
 		return
 	}
 
-	_ = json.NewEncoder(w).Encode(listResponse{Items: listings})
+	total, err := s.svc.CountListings(r.Context(), lq.filter)
+	if err != nil {
+		s.logger.Error("count listings failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to count listings")
+		return
+	}
+
+	if listings == nil {
+		listings = make([]*model.Listing, 0)
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{
+		Items:      listings,
+		Total:      total,
+		Page:       lq.page,
+		Limit:      lq.limit,
+		TotalPages: (total + int64(lq.limit) - 1) / int64(lq.limit),
+		Next:       lq.nextPageURL(r.URL, total),
+	})
+}
+
+func (s *Server) handlePriceHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	changes, err := s.svc.GetPriceHistory(r.Context(), id)
+	if errors.Is(err, service.ErrListingNotFound) {
+		writeError(w, http.StatusNotFound, codeNotFound, "listing not found")
+		return
+	}
+	if err != nil {
+		s.logger.Error("get price history failed", zap.String("listing_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch price history")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, priceHistoryResponse{ListingID: id, Items: changes})
 }
 
 func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
 	site := r.URL.Query().Get("site")
 	if site == "" {
-		http.Error(w, "missing site param", http.StatusBadRequest)
+		writeInvalidParam(w, invalidParam("site", "is required"))
+		return
+	}
+
+	if !s.svc.HasSite(site) {
+		writeError(w, http.StatusNotFound, codeNotFound, "site not found")
+		return
+	}
+
+	job, err := s.queue.Enqueue(r.Context(), site, r.URL.Query().Get("url"))
+	if errors.Is(err, scheduler.ErrAlreadyQueued) || errors.Is(err, scheduler.ErrQuietHours) {
+		writeError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Error("enqueue scrape failed", zap.String("site", site), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to queue scrape")
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.JobID)
+	writeJSON(w, http.StatusAccepted, triggerResponse{Status: job.Status, JobID: job.JobID})
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	jq, apiErr := parseJobQuery(r.URL.Query())
+	if apiErr != nil {
+		writeInvalidParam(w, apiErr)
+		return
+	}
+
+	jobs, err := s.svc.ListJobs(r.Context(), jq.filter)
+	if err != nil {
+		s.logger.Error("list jobs failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch jobs")
+		return
+	}
+
+	total, err := s.svc.CountJobs(r.Context(), jq.filter)
+	if err != nil {
+		s.logger.Error("count jobs failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to count jobs")
+		return
+	}
+
+	if jobs == nil {
+		jobs = make([]*model.JobStatus, 0)
+	}
+
+	writeJSON(w, http.StatusOK, jobListResponse{
+		Items: jobs,
+		Total: total,
+		Page:  jq.page,
+		Limit: jq.limit,
+	})
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	job, err := s.svc.GetJob(r.Context(), id)
+	if errors.Is(err, service.ErrJobNotFound) {
+		writeError(w, http.StatusNotFound, codeNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		s.logger.Error("get job failed", zap.String("job_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch job")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	job, err := s.svc.GetJob(r.Context(), id)
+	if errors.Is(err, service.ErrJobNotFound) {
+		writeError(w, http.StatusNotFound, codeNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		s.logger.Error("get job failed", zap.String("job_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch job")
+		return
+	}
+
+	if job.Status != model.JobStatusQueued && job.Status != model.JobStatusRunning {
+		writeError(w, http.StatusConflict, codeConflict, "job already "+job.Status)
 		return
 	}
 
-	url := r.URL.Query().Get("url")
+	if err := s.queue.CancelJob(r.Context(), id); err != nil {
+		s.logger.Error("cancel job failed", zap.String("job_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to cancel job")
+		return
+	}
 
-	go func() {
-		if err := s.svc.ScrapeWebsite(r.Context(), site, url); err != nil {
-			s.logger.Warn("background scrape failed", zap.Error(err))
-			_ = s.notifier.NotifyError(r.Context(), site, err)
-		} else {
-			s.logger.Info("background scrape triggered", zap.String("site", site))
-		}
-	}()
+	writeJSON(w, http.StatusAccepted, triggerResponse{Status: "cancelling", JobID: id})
+}
+
+func (s *Server) handleQueueStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.queue.ExecutorStats(r.Context())
+	if err != nil {
+		s.logger.Error("get queue stats failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch queue stats")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+type workerListResponse struct {
+	Items []scheduler.WorkerInfo `json:"items"`
+	Locks []scheduler.SiteLock   `json:"locks"`
+}
+
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	workers, err := s.workers.ListWorkers(r.Context())
+	if err != nil {
+		s.logger.Error("list workers failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch workers")
+		return
+	}
+
+	locks, err := s.workers.ListLocks(r.Context(), workers)
+	if err != nil {
+		s.logger.Error("list locks failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch locks")
+		return
+	}
+	if locks == nil {
+		locks = []scheduler.SiteLock{}
+	}
+
+	writeJSON(w, http.StatusOK, workerListResponse{Items: workers, Locks: locks})
+}
+
+func (s *Server) handleLastRun(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	job, err := s.svc.LastRun(r.Context(), name)
+	switch {
+	case errors.Is(err, service.ErrSiteNotFound):
+		writeError(w, http.StatusNotFound, codeNotFound, "site not found")
+		return
+	case errors.Is(err, service.ErrJobNotFound):
+		writeError(w, http.StatusNotFound, codeNotFound, "site has no recorded runs")
+		return
+	case err != nil:
+		s.logger.Error("get last run failed", zap.String("site", name), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to fetch last run")
+		return
+	}
 
-	w.WriteHeader(http.StatusAccepted)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+	writeJSON(w, http.StatusOK, job)
 }
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/geo"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
+)
+
+// Pagination bounds for GET /listings and GET /jobs
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// listingQuery is a validated GET /listings request
+type listingQuery struct {
+	filter *storage.ListingFilter
+	page   int
+	limit  int
+}
+
+// parseListingQuery validates the query parameters of GET /listings and
+// turns them into a repository filter
+func parseListingQuery(q url.Values) (*listingQuery, *apiError) {
+	p := queryParser{values: q}
+
+	f := &storage.ListingFilter{
+		SiteName:     q.Get("site"),
+		ListingType:  q.Get("listing_type"),
+		RentPeriod:   q.Get("rent_period"),
+		PropertyType: q.Get("property_type"),
+		Location:     q.Get("location"),
+		Status:       q.Get("status"),
+	}
+
+	f.MinPrice = p.floatParam("min_price")
+	f.MaxPrice = p.floatParam("max_price")
+	f.MinBedrooms = p.intParam("min_bedrooms", 0)
+	f.MinBathrooms = p.intParam("min_bathrooms", 0)
+	f.PriceDroppedWithinDays = p.intParam("price_dropped_days", 1)
+	f.MinDaysOnMarket = p.intParam("min_days_on_market", 0)
+	f.MaxDaysOnMarket = p.intParam("max_days_on_market", 0)
+
+	page, limit := p.pagination()
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if f.MaxPrice > 0 && f.MinPrice > f.MaxPrice {
+		return nil, invalidParam("min_price", "must not be greater than max_price")
+	}
+	if f.MaxDaysOnMarket > 0 && f.MinDaysOnMarket > f.MaxDaysOnMarket {
+		return nil, invalidParam("min_days_on_market", "must not be greater than max_days_on_market")
+	}
+	if f.PropertyType != "" && !slices.Contains(model.PropertyTypes, f.PropertyType) {
+		return nil, invalidParam("property_type", fmt.Sprintf("must be one of %v", model.PropertyTypes))
+	}
+	if apiErr := parseRegionCodes(q, f); apiErr != nil {
+		return nil, apiErr
+	}
+	if f.Status != "" && f.Status != model.ListingStatusActive && f.Status != model.ListingStatusInactive {
+		return nil, invalidParam("status", "must be active or inactive")
+	}
+
+	if sort := q.Get("sort"); sort != "" {
+		if !slices.Contains(storage.ListingSortFields, sort) {
+			return nil, invalidParam("sort", fmt.Sprintf("must be one of %v", storage.ListingSortFields))
+		}
+		f.SortBy = sort
+	}
+	if apiErr := checkPriceBasis(f); apiErr != nil {
+		return nil, apiErr
+	}
+	switch q.Get("order") {
+	case "", "desc":
+		f.SortDesc = true
+	case "asc":
+		f.SortDesc = false
+	default:
+		return nil, invalidParam("order", "must be asc or desc")
+	}
+
+	f.Limit = limit
+	f.Offset = (page - 1) * limit
+
+	return &listingQuery{filter: f, page: page, limit: limit}, nil
+}
+
+// parseRegionCodes reads the address filters of a listing query, which must
+// be codes of regions of the gazetteer at the level the parameter names
+func parseRegionCodes(q url.Values, f *storage.ListingFilter) *apiError {
+	params := []struct {
+		name  string
+		level int
+		code  *string
+	}{
+		{"province_code", geo.LevelProvince, &f.ProvinceCode},
+		{"city_code", geo.LevelCity, &f.CityCode},
+		{"district_code", geo.LevelDistrict, &f.DistrictCode},
+		{"village_code", geo.LevelVillage, &f.VillageCode},
+	}
+
+	for _, p := range params {
+		code := q.Get(p.name)
+		if code == "" {
+			continue
+		}
+		if region := geo.Default().Region(code); region == nil || region.Level != p.level {
+			return invalidParam(p.name, "must be a known region code of that level")
+		}
+		*p.code = code
+	}
+	return nil
+}
+
+// checkPriceBasis validates the listing type and rent period of a listing
+// filter. Sale and rent prices are never compared: filtering or sorting by
+// price implies sale listings unless rent is asked for, and rent listings
+// must then be of a single rent period.
+func checkPriceBasis(f *storage.ListingFilter) *apiError {
+	switch f.ListingType {
+	case "", model.ListingTypeSale, model.ListingTypeRent:
+	default:
+		return invalidParam("listing_type", "must be sale or rent")
+	}
+
+	switch f.RentPeriod {
+	case "":
+	case model.PricePeriodMonthly, model.PricePeriodYearly:
+		if f.ListingType == model.ListingTypeSale {
+			return invalidParam("rent_period", "applies to rent listings only")
+		}
+		f.ListingType = model.ListingTypeRent
+	default:
+		return invalidParam("rent_period", "must be monthly or yearly")
+	}
+
+	byPrice := f.MinPrice > 0 || f.MaxPrice > 0 || f.SortBy == "price"
+	if !byPrice {
+		return nil
+	}
+	if f.ListingType == "" {
+		f.ListingType = model.ListingTypeSale
+	}
+	if f.ListingType == model.ListingTypeRent && f.RentPeriod == "" {
+		return invalidParam("rent_period", "is required to filter or sort rent listings by price")
+	}
+	return nil
+}
+
+// jobStatuses are the run statuses GET /jobs can filter on
+var jobStatuses = []string{
+	model.JobStatusQueued,
+	model.JobStatusRunning,
+	model.JobStatusCompleted,
+	model.JobStatusFailed,
+	model.JobStatusCancelled,
+	model.JobStatusInterrupted,
+}
+
+// jobQuery is a validated GET /jobs request
+type jobQuery struct {
+	filter *storage.RunFilter
+	page   int
+	limit  int
+}
+
+// parseJobQuery validates the query parameters of GET /jobs
+func parseJobQuery(q url.Values) (*jobQuery, *apiError) {
+	p := queryParser{values: q}
+
+	f := &storage.RunFilter{
+		SiteName: q.Get("site"),
+		Status:   q.Get("status"),
+	}
+
+	page, limit := p.pagination()
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if f.Status != "" && !slices.Contains(jobStatuses, f.Status) {
+		return nil, invalidParam("status", fmt.Sprintf("must be one of %v", jobStatuses))
+	}
+
+	f.Limit = limit
+	f.Offset = (page - 1) * limit
+
+	return &jobQuery{filter: f, page: page, limit: limit}, nil
+}
+
+// nextPageURL returns the request URL pointing at the following page, or an
+// empty string if page is the last one
+func (lq *listingQuery) nextPageURL(u *url.URL, total int64) string {
+	if int64(lq.page*lq.limit) >= total {
+		return ""
+	}
+
+	q := u.Query()
+	q.Set("page", strconv.Itoa(lq.page+1))
+	q.Set("limit", strconv.Itoa(lq.limit))
+
+	next := url.URL{Path: u.Path, RawQuery: q.Encode()}
+	return next.String()
+}
+
+// queryParser reads numeric parameters and keeps the first validation error
+type queryParser struct {
+	values url.Values
+	err    *apiError
+}
+
+// pagination reads page and limit, applying the defaults and the page size cap
+func (p *queryParser) pagination() (page, limit int) {
+	page = p.intParam("page", 1)
+	limit = p.intParam("limit", 1)
+	if p.err != nil {
+		return 0, 0
+	}
+
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		p.err = invalidParam("limit", fmt.Sprintf("must be at most %d", maxPageLimit))
+		return 0, 0
+	}
+	return page, limit
+}
+
+// intParam parses a non-negative integer parameter that must be at least
+// atLeast when present; absent parameters yield zero
+func (p *queryParser) intParam(name string, atLeast int) int {
+	v := p.values.Get(name)
+	if v == "" || p.err != nil {
+		return 0
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < atLeast || n < 0 {
+		p.err = invalidParam(name, fmt.Sprintf("must be an integer of at least %d", max(atLeast, 0)))
+		return 0
+	}
+	return n
+}
+
+// floatParam parses a non-negative number parameter; absent parameters yield zero
+func (p *queryParser) floatParam(name string) float64 {
+	v := p.values.Get(name)
+	if v == "" || p.err != nil {
+		return 0
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+		p.err = invalidParam(name, "must be a non-negative number")
+		return 0
+	}
+	return n
+}
+
+func invalidParam(field, message string) *apiError {
+	return &apiError{
+		Code:    codeInvalidParameter,
+		Message: field + " " + message,
+		Field:   field,
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseListingQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantField string
+		wantPage  int
+		wantLimit int
+	}{
+		{name: "defaults", query: "", wantPage: 1, wantLimit: defaultPageLimit},
+		{name: "page and limit", query: "page=3&limit=50", wantPage: 3, wantLimit: 50},
+		{name: "all filters", query: "site=rumah123&min_price=100&max_price=200&min_bedrooms=2&status=active&sort=price&order=asc", wantPage: 1, wantLimit: defaultPageLimit},
+		{name: "non numeric price", query: "min_price=abc", wantField: "min_price"},
+		{name: "negative bedrooms", query: "min_bedrooms=-1", wantField: "min_bedrooms"},
+		{name: "zero page", query: "page=0", wantField: "page"},
+		{name: "limit too large", query: "limit=101", wantField: "limit"},
+		{name: "inverted price range", query: "min_price=300&max_price=200", wantField: "min_price"},
+		{name: "inverted days on market", query: "min_days_on_market=10&max_days_on_market=5", wantField: "min_days_on_market"},
+		{name: "unknown status", query: "status=sold", wantField: "status"},
+		{name: "rent by price", query: "listing_type=rent&rent_period=monthly&max_price=5000000&sort=price", wantPage: 1, wantLimit: defaultPageLimit},
+		{name: "property type", query: "property_type=ruko", wantPage: 1, wantLimit: defaultPageLimit},
+		{name: "unknown property type", query: "property_type=kost", wantField: "property_type"},
+		{name: "region codes", query: "province_code=31&city_code=31.71&district_code=31.71.01&village_code=31.71.01.1010", wantPage: 1, wantLimit: defaultPageLimit},
+		{name: "unknown city code", query: "city_code=99.99", wantField: "city_code"},
+		{name: "code of other level", query: "district_code=31.71", wantField: "district_code"},
+		{name: "unknown listing type", query: "listing_type=lease", wantField: "listing_type"},
+		{name: "unknown rent period", query: "rent_period=weekly", wantField: "rent_period"},
+		{name: "rent period of sale", query: "listing_type=sale&rent_period=monthly", wantField: "rent_period"},
+		{name: "rent by price without period", query: "listing_type=rent&sort=price", wantField: "rent_period"},
+		{name: "unknown sort", query: "sort=title", wantField: "sort"},
+		{name: "unknown order", query: "order=up", wantField: "order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("bad test query: %v", err)
+			}
+
+			lq, apiErr := parseListingQuery(q)
+			if tt.wantField != "" {
+				if apiErr == nil {
+					t.Fatalf("expected error on %s, got none", tt.wantField)
+				}
+				if apiErr.Field != tt.wantField {
+					t.Fatalf("expected error on %s, got %s", tt.wantField, apiErr.Field)
+				}
+				return
+			}
+
+			if apiErr != nil {
+				t.Fatalf("unexpected error: %v", apiErr)
+			}
+			if lq.page != tt.wantPage || lq.limit != tt.wantLimit {
+				t.Fatalf("expected page %d limit %d, got page %d limit %d", tt.wantPage, tt.wantLimit, lq.page, lq.limit)
+			}
+			if lq.filter.Offset != (tt.wantPage-1)*tt.wantLimit {
+				t.Fatalf("unexpected offset %d", lq.filter.Offset)
+			}
+		})
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	u, _ := url.Parse("/listings?site=rumah123&page=1&limit=10")
+	lq := &listingQuery{page: 1, limit: 10}
+
+	if got := lq.nextPageURL(u, 25); got != "/listings?limit=10&page=2&site=rumah123" {
+		t.Fatalf("unexpected next page url %q", got)
+	}
+	if got := lq.nextPageURL(u, 10); got != "" {
+		t.Fatalf("expected no next page, got %q", got)
+	}
+}
+
+func TestParseListingQueryPriceBasis(t *testing.T) {
+	tests := []struct {
+		query          string
+		wantType       string
+		wantRentPeriod string
+	}{
+		{query: "", wantType: ""},
+		{query: "site=rumah123", wantType: ""},
+		{query: "min_price=100", wantType: "sale"},
+		{query: "sort=price", wantType: "sale"},
+		{query: "listing_type=rent", wantType: "rent"},
+		{query: "rent_period=yearly", wantType: "rent", wantRentPeriod: "yearly"},
+		{query: "rent_period=monthly&max_price=100", wantType: "rent", wantRentPeriod: "monthly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			lq, apiErr := parseListingQuery(q)
+			if apiErr != nil {
+				t.Fatalf("unexpected error: %v", apiErr)
+			}
+			if lq.filter.ListingType != tt.wantType || lq.filter.RentPeriod != tt.wantRentPeriod {
+				t.Fatalf("expected %q/%q, got %q/%q", tt.wantType, tt.wantRentPeriod, lq.filter.ListingType, lq.filter.RentPeriod)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes returned in error responses
+const (
+	codeInvalidParameter = "invalid_parameter"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeInternal         = "internal_error"
+)
+
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// apiError is the body of every error response
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: apiError{Code: code, Message: message}})
+}
+
+func writeInvalidParam(w http.ResponseWriter, err *apiError) {
+	writeJSON(w, http.StatusBadRequest, errorResponse{Error: *err})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/scheduler"
+)
+
+type scheduleListResponse struct {
+	Items []scheduler.ScheduleInfo `json:"items"`
+}
+
+type scheduleRequest struct {
+	Schedule string `json:"schedule"`
+	URL      string `json:"url"`
+}
+
+func (s *Server) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, scheduleListResponse{Items: s.schedules.ListSchedules()})
+}
+
+func (s *Server) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	info, err := s.schedules.GetSchedule(r.PathValue("site"))
+	if err != nil {
+		s.writeScheduleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handlePutSchedule(w http.ResponseWriter, r *http.Request) {
+	site := r.PathValue("site")
+	if !s.svc.HasSite(site) {
+		writeError(w, http.StatusNotFound, codeNotFound, "site not found")
+		return
+	}
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "request body must be a JSON object")
+		return
+	}
+	if req.Schedule == "" {
+		writeInvalidParam(w, invalidParam("schedule", "is required"))
+		return
+	}
+
+	sch, err := s.schedules.PutSchedule(r.Context(), site, req.Schedule, req.URL)
+	if err != nil {
+		s.writeScheduleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sch)
+}
+
+func (s *Server) handlePauseSchedule(w http.ResponseWriter, r *http.Request) {
+	sch, err := s.schedules.PauseSchedule(r.Context(), r.PathValue("site"))
+	if err != nil {
+		s.writeScheduleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sch)
+}
+
+func (s *Server) handleResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	sch, err := s.schedules.ResumeSchedule(r.Context(), r.PathValue("site"))
+	if err != nil {
+		s.writeScheduleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sch)
+}
+
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := s.schedules.DeleteSchedule(r.Context(), r.PathValue("site")); err != nil {
+		s.writeScheduleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) writeScheduleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrScheduleNotFound):
+		writeError(w, http.StatusNotFound, codeNotFound, "schedule not found")
+	case errors.Is(err, scheduler.ErrInvalidSchedule):
+		writeInvalidParam(w, &apiError{Code: codeInvalidParameter, Message: err.Error(), Field: "schedule"})
+	default:
+		s.logger.Error("schedule request failed",
+			zap.String("site", r.PathValue("site")),
+			zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to update schedule")
+	}
+}
@@ -7,7 +7,8 @@ import (
 	"time"
 
 	"github.com/Alwanly/Houses-Prices/worker/internal/config"
-	"github.com/Alwanly/Houses-Prices/worker/internal/notification"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/scheduler"
 	"github.com/Alwanly/Houses-Prices/worker/internal/service"
 	"go.uber.org/zap"
 )
@@ -15,23 +16,80 @@ import (
 type Server struct {
 	httpServer *http.Server
 	svc        *service.ScraperService
-	notifier   *notification.Notifier
+	queue      JobQueue
+	schedules  ScheduleManager
+	workers    WorkerRegistry
 	logger     *zap.Logger
 	cfg        *config.ServerConfig
 }
 
-func NewServer(cfg *config.ServerConfig, svc *service.ScraperService, notifier *notification.Notifier, logger *zap.Logger) *Server {
+// JobQueue queues manually triggered scrapes for the workers to run,
+// cancels queued or running scrapes, reports the queue and executor state
+// and manages the dead-letter list of scheduled runs that failed every retry
+type JobQueue interface {
+	Enqueue(ctx context.Context, siteName, url string) (*model.JobStatus, error)
+	CancelJob(ctx context.Context, jobID string) error
+	ExecutorStats(ctx context.Context) (*scheduler.ExecutorStats, error)
+	ListDeadLetters(ctx context.Context) ([]scheduler.DeadLetter, error)
+	ReplayDeadLetter(ctx context.Context, jobID string) (*model.JobStatus, error)
+	DeleteDeadLetter(ctx context.Context, jobID string) error
+}
+
+// ScheduleManager changes the cron schedules of all workers at runtime
+type ScheduleManager interface {
+	ListSchedules() []scheduler.ScheduleInfo
+	GetSchedule(siteName string) (*scheduler.ScheduleInfo, error)
+	PutSchedule(ctx context.Context, siteName, spec, url string) (*scheduler.Schedule, error)
+	PauseSchedule(ctx context.Context, siteName string) (*scheduler.Schedule, error)
+	ResumeSchedule(ctx context.Context, siteName string) (*scheduler.Schedule, error)
+	DeleteSchedule(ctx context.Context, siteName string) error
+}
+
+// WorkerRegistry lists the live workers, the site locks they hold and the
+// elected leader
+type WorkerRegistry interface {
+	ListWorkers(ctx context.Context) ([]scheduler.WorkerInfo, error)
+	ListLocks(ctx context.Context, live []scheduler.WorkerInfo) ([]scheduler.SiteLock, error)
+	Leader(ctx context.Context) (string, error)
+}
+
+func NewServer(
+	cfg *config.ServerConfig,
+	svc *service.ScraperService,
+	queue JobQueue,
+	schedules ScheduleManager,
+	workers WorkerRegistry,
+	logger *zap.Logger,
+) *Server {
 	mux := http.NewServeMux()
 	s := &Server{
-		svc:      svc,
-		notifier: notifier,
-		logger:   logger,
-		cfg:      cfg,
+		svc:       svc,
+		queue:     queue,
+		schedules: schedules,
+		workers:   workers,
+		logger:    logger,
+		cfg:       cfg,
 	}
 
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/listings", s.handleList)
-	mux.HandleFunc("/scrape", s.handleTrigger)
+	mux.HandleFunc("GET /listings/{id}/price-history", s.handlePriceHistory)
+	mux.HandleFunc("POST /scrape", s.handleTrigger)
+	mux.HandleFunc("GET /jobs", s.handleJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleJob)
+	mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancelJob)
+	mux.HandleFunc("GET /sites/{name}/last-run", s.handleLastRun)
+	mux.HandleFunc("GET /queue", s.handleQueueStats)
+	mux.HandleFunc("GET /workers", s.handleWorkers)
+	mux.HandleFunc("GET /dead-letters", s.handleDeadLetters)
+	mux.HandleFunc("POST /dead-letters/{id}/replay", s.handleReplayDeadLetter)
+	mux.HandleFunc("DELETE /dead-letters/{id}", s.handleDeleteDeadLetter)
+	mux.HandleFunc("GET /schedules", s.handleListSchedules)
+	mux.HandleFunc("GET /schedules/{site}", s.handleGetSchedule)
+	mux.HandleFunc("PUT /schedules/{site}", s.handlePutSchedule)
+	mux.HandleFunc("POST /schedules/{site}/pause", s.handlePauseSchedule)
+	mux.HandleFunc("POST /schedules/{site}/resume", s.handleResumeSchedule)
+	mux.HandleFunc("DELETE /schedules/{site}", s.handleDeleteSchedule)
 
 	s.httpServer = &http.Server{
 		Addr:         ":" + strconv.Itoa(cfg.Port),
@@ -2,11 +2,12 @@ package config
 
 // Config represents the application configuration
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server" validate:"required"`
-	MongoDB MongoDBConfig `mapstructure:"mongodb" validate:"required"`
-	Redis   RedisConfig   `mapstructure:"redis" validate:"required"`
-	Logging LoggingConfig `mapstructure:"logging" validate:"required"`
-	Sites   []SiteConfig  `mapstructure:"sites" validate:"required,min=1,dive"`
+	Server    ServerConfig    `mapstructure:"server" validate:"required"`
+	MongoDB   MongoDBConfig   `mapstructure:"mongodb" validate:"required"`
+	Redis     RedisConfig     `mapstructure:"redis" validate:"required"`
+	Logging   LoggingConfig   `mapstructure:"logging" validate:"required"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Sites     []SiteConfig    `mapstructure:"sites" validate:"required,min=1,dive"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -35,15 +36,120 @@ type LoggingConfig struct {
 	Format string `mapstructure:"format" validate:"required,oneof=json console"`
 }
 
+// SchedulerConfig holds settings shared by all scheduled jobs
+type SchedulerConfig struct {
+	// CatchUpWindow is the number of seconds after a missed scheduled run
+	// during which a starting worker still runs it; zero disables catch-up
+	CatchUpWindow int `mapstructure:"catch_up_window" validate:"min=0"`
+
+	// Timezone is the IANA time zone of site schedules and quiet hours that
+	// do not set their own; empty means the host time zone
+	Timezone string `mapstructure:"timezone" validate:"omitempty,timezone"`
+
+	// Holidays lists public holidays as YYYY-MM-DD; sites with skip_holidays
+	// are not crawled on these days
+	Holidays []string `mapstructure:"holidays" validate:"dive,datetime=2006-01-02"`
+
+	// Workers is the number of runs a worker executes at once across all
+	// sites; zero uses the scheduler default
+	Workers int `mapstructure:"workers" validate:"min=0"`
+
+	// LeaderElection lets only one elected worker fire the schedules; it
+	// pushes their runs onto the shared queue for any worker to run
+	LeaderElection bool `mapstructure:"leader_election"`
+}
+
 // SiteConfig holds configuration for a scraping target site
 type SiteConfig struct {
 	Name      string         `mapstructure:"name" validate:"required"`
+	Scraper   string         `mapstructure:"scraper"` // registered scraper; defaults to the site name, then generic
 	BaseURL   string         `mapstructure:"base_url" validate:"required,url"`
 	Schedule  string         `mapstructure:"schedule" validate:"required"`
 	Enabled   bool           `mapstructure:"enabled"`
 	RateLimit int            `mapstructure:"rate_limit" validate:"min=1"`
 	Timeout   int            `mapstructure:"timeout" validate:"min=1"`
 	Selectors SelectorConfig `mapstructure:"selectors" validate:"required"`
+
+	// ListingType is what the site's listings are offered for, sale or rent;
+	// empty means sale. RentPeriod is the period of a rent price that does not
+	// state one, monthly unless set. A price with a rent suffix ("/bulan",
+	// "/tahun") makes a listing a rent listing on any site.
+	ListingType string `mapstructure:"listing_type" validate:"omitempty,oneof=sale rent"`
+	RentPeriod  string `mapstructure:"rent_period" validate:"omitempty,oneof=monthly yearly"`
+
+	// PropertyTypes overrides the property type of listings matching a rule;
+	// the first matching rule wins over the built-in classification
+	PropertyTypes []PropertyTypeRule `mapstructure:"property_types" validate:"dive"`
+
+	// Crawl budget per run; zero means unlimited
+	MaxPages    int `mapstructure:"max_pages" validate:"min=0"`
+	MaxListings int `mapstructure:"max_listings" validate:"min=0"`
+
+	// StreamBuffer is the number of scraped listings that may wait for saving
+	// before the crawl is slowed down; zero uses the service default
+	StreamBuffer int `mapstructure:"stream_buffer" validate:"min=0"`
+
+	// SaveBatchSize is the number of listings written per bulk save; zero uses
+	// the service default
+	SaveBatchSize int `mapstructure:"save_batch_size" validate:"min=0"`
+
+	// DelistAfterRuns is the number of consecutive full crawls a listing may be
+	// missing from before it is marked inactive; zero uses the service default
+	DelistAfterRuns int `mapstructure:"delist_after_runs" validate:"min=0"`
+
+	// MaxDuration is the number of seconds a run may take before it is
+	// cancelled; zero means unlimited
+	MaxDuration int `mapstructure:"max_duration" validate:"min=0"`
+
+	// Timing of scheduled runs. Timezone overrides scheduler.timezone, Jitter
+	// delays every scheduled start by up to that many seconds, and no run
+	// starts or keeps running during QuietHours ("HH:MM-HH:MM" in the site's
+	// time zone, may wrap midnight) or, with SkipHolidays, on a holiday.
+	Timezone     string   `mapstructure:"timezone" validate:"omitempty,timezone"`
+	Jitter       int      `mapstructure:"jitter" validate:"min=0"`
+	QuietHours   []string `mapstructure:"quiet_hours"`
+	SkipHolidays bool     `mapstructure:"skip_holidays"`
+
+	// Retry is the retry policy of failed scheduled runs
+	Retry JobRetryConfig `mapstructure:"retry"`
+
+	// Adaptive makes the crawl frequency follow the site's churn
+	Adaptive AdaptiveConfig `mapstructure:"adaptive"`
+
+	// Detail-page enrichment. DetailMode selects which listings are visited:
+	// off, all, new (not stored yet) or changed (new or different from the stored copy).
+	// An empty mode means all when detail selectors are configured.
+	DetailSelectors DetailSelectorConfig `mapstructure:"detail_selectors"`
+	DetailMode      string               `mapstructure:"detail_mode" validate:"omitempty,oneof=off all new changed"`
+	DetailRateLimit int                  `mapstructure:"detail_rate_limit" validate:"min=0"`
+}
+
+// JobRetryConfig holds the retry policy of failed scheduled runs. A run that
+// fails MaxAttempts more times goes to the dead-letter list.
+type JobRetryConfig struct {
+	MaxAttempts int `mapstructure:"max_attempts" validate:"min=0"` // retries after the first failure
+	Backoff     int `mapstructure:"backoff" validate:"min=0"`      // seconds before the first retry, doubled for each further one
+	MaxBackoff  int `mapstructure:"max_backoff" validate:"min=0"`  // upper bound of the backoff in seconds; zero means unbounded
+}
+
+// AdaptiveConfig adapts the crawl frequency of a site to its churn, the share
+// of saved listings that were new or changed price in recent runs. The
+// schedule then only sets the times a run may start at; a run is skipped
+// until the interval derived from churn has passed since the last one.
+type AdaptiveConfig struct {
+	MinInterval int     `mapstructure:"min_interval" validate:"min=0"`                          // seconds between runs at or above the target churn
+	MaxInterval int     `mapstructure:"max_interval" validate:"omitempty,gtefield=MinInterval"` // seconds between runs without churn; zero disables
+	Runs        int     `mapstructure:"runs" validate:"min=0"`                                  // completed runs churn is measured over; zero uses the default
+	TargetChurn float64 `mapstructure:"target_churn" validate:"min=0,max=1"`                    // churn that gets the min interval; zero uses the default
+}
+
+// PropertyTypeRule sets the property type of listings whose URL, site label
+// or title matches Pattern, a case-insensitive regular expression. An empty
+// Source matches any of them.
+type PropertyTypeRule struct {
+	Pattern string `mapstructure:"pattern" validate:"required"`
+	Source  string `mapstructure:"source" validate:"omitempty,oneof=url label title"`
+	Type    string `mapstructure:"type" validate:"required,oneof=rumah apartemen tanah ruko gudang"`
 }
 
 // SelectorConfig holds CSS selectors for extracting data
@@ -62,4 +168,19 @@ type SelectorConfig struct {
 	AgentName    string `mapstructure:"agent_name"`
 	AgentPhone   string `mapstructure:"agent_phone"`
 	NextPage     string `mapstructure:"next_page"`
+
+	// PropertyType selects the property type label a site shows on its cards
+	PropertyType string `mapstructure:"property_type"`
+}
+
+// DetailSelectorConfig holds CSS selectors applied to a listing's detail page
+type DetailSelectorConfig struct {
+	Description  string `mapstructure:"description"`
+	Images       string `mapstructure:"images"`
+	AgentName    string `mapstructure:"agent_name"`
+	AgentPhone   string `mapstructure:"agent_phone"`
+	Bedrooms     string `mapstructure:"bedrooms"`
+	Bathrooms    string `mapstructure:"bathrooms"`
+	LandArea     string `mapstructure:"land_area"`
+	BuildingArea string `mapstructure:"building_area"`
 }
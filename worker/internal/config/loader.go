@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -37,6 +38,13 @@ func Load(path string) (*Config, error) {
 	if err := validate.Struct(&cfg); err != nil {
 		return nil, fmt.Errorf("validating config: %w", err)
 	}
+	for _, site := range cfg.Sites {
+		for i, rule := range site.PropertyTypes {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("validating config: site %s property_types[%d]: %w", site.Name, i, err)
+			}
+		}
+	}
 
 	return &cfg, nil
 }
@@ -0,0 +1,147 @@
+// Package geo resolves free-text Indonesian locations to the administrative
+// hierarchy of an embedded gazetteer
+package geo
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Region levels, from provinsi down to kelurahan/desa
+const (
+	LevelProvince = 1
+	LevelCity     = 2
+	LevelDistrict = 3
+	LevelVillage  = 4
+)
+
+// Region is an entry of the gazetteer
+type Region struct {
+	Code    string
+	Name    string
+	Level   int
+	Aliases []string
+}
+
+// Gazetteer indexes regions by code and by the keys of their names
+type Gazetteer struct {
+	byCode map[string]*Region
+	byKey  map[string][]*Region
+
+	// maxWords is the number of words of the longest name
+	maxWords int
+}
+
+//go:embed data/gazetteer.csv
+var gazetteerCSV []byte
+
+// Default returns the gazetteer embedded in the binary
+var Default = sync.OnceValue(func() *Gazetteer {
+	g, err := Parse(bytes.NewReader(gazetteerCSV))
+	if err != nil {
+		panic(fmt.Sprintf("parsing embedded gazetteer: %v", err))
+	}
+	return g
+})
+
+// Parse reads a gazetteer from CSV with a header and the columns code, name
+// and aliases. Codes are dot-separated with one segment per level, and
+// aliases are separated by "|". Parents must come before their children.
+func Parse(r io.Reader) (*Gazetteer, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+
+	if _, err := cr.Read(); err != nil {
+		return nil, fmt.Errorf("reading gazetteer header: %w", err)
+	}
+
+	g := &Gazetteer{
+		byCode: make(map[string]*Region),
+		byKey:  make(map[string][]*Region),
+	}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading gazetteer: %w", err)
+		}
+
+		region := &Region{
+			Code:  record[0],
+			Name:  record[1],
+			Level: strings.Count(record[0], ".") + 1,
+		}
+		if record[2] != "" {
+			region.Aliases = strings.Split(record[2], "|")
+		}
+		if err := g.add(region); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (g *Gazetteer) add(region *Region) error {
+	if region.Level > LevelVillage {
+		return fmt.Errorf("region %s: too many levels", region.Code)
+	}
+	if _, ok := g.byCode[region.Code]; ok {
+		return fmt.Errorf("region %s: duplicate code", region.Code)
+	}
+	if region.Level > LevelProvince {
+		if _, ok := g.byCode[parentCode(region.Code)]; !ok {
+			return fmt.Errorf("region %s: unknown parent", region.Code)
+		}
+	}
+	g.byCode[region.Code] = region
+
+	names := append([]string{region.Name}, region.Aliases...)
+	if region.Level == LevelCity {
+		// "Bogor" names both Kota and Kabupaten Bogor
+		for _, prefix := range []string{"Kota ", "Kabupaten "} {
+			if bare, ok := strings.CutPrefix(region.Name, prefix); ok {
+				names = append(names, bare)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		words := tokenize(name)
+		key := strings.Join(words, "")
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		g.byKey[key] = append(g.byKey[key], region)
+		g.maxWords = max(g.maxWords, len(words))
+	}
+	return nil
+}
+
+// Region returns the region with the given code, or nil
+func (g *Gazetteer) Region(code string) *Region {
+	return g.byCode[code]
+}
+
+// parentCode returns the code of the region containing code
+func parentCode(code string) string {
+	i := strings.LastIndexByte(code, '.')
+	if i < 0 {
+		return ""
+	}
+	return code[:i]
+}
+
+// isAncestorOrSelf reports whether region a contains region b or is b
+func isAncestorOrSelf(a, b *Region) bool {
+	return a.Code == b.Code || strings.HasPrefix(b.Code, a.Code+".")
+}
@@ -0,0 +1,189 @@
+package geo
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// abbreviations expands the abbreviated words of listing locations, so that
+// "Kab. Bogor", "Jak-Sel" and "Kby Baru" match the gazetteer names
+var abbreviations = map[string]string{
+	"kab":  "kabupaten",
+	"kec":  "kecamatan",
+	"kel":  "kelurahan",
+	"ds":   "desa",
+	"prov": "provinsi",
+	"adm":  "administrasi",
+	"jkt":  "jakarta",
+	"jak":  "jakarta",
+	"sel":  "selatan",
+	"sltn": "selatan",
+	"tim":  "timur",
+	"tmr":  "timur",
+	"bar":  "barat",
+	"brt":  "barat",
+	"ut":   "utara",
+	"utr":  "utara",
+	"pst":  "pusat",
+	"br":   "baru",
+	"kby":  "kebayoran",
+	"klp":  "kelapa",
+	"pd":   "pondok",
+	"ps":   "pasar",
+	"psr":  "pasar",
+	"tj":   "tanjung",
+	"tg":   "tanjung",
+}
+
+// Normalize resolves a free-text location such as "Kebayoran Baru, Jakarta
+// Selatan" or "Kab. Bogor" to the deepest region it names. Every run of
+// words matching a region name counts as a mention; the region mentioned or
+// contained by the most mentions wins, and when mentions disagree the
+// location resolves to the smallest region containing all contenders, e.g.
+// the province for "Bogor" (Kota or Kabupaten). A location naming no known
+// region yields nil.
+func (g *Gazetteer) Normalize(location string) *model.Address {
+	var mentions [][]*Region
+	for _, part := range splitParts(location) {
+		words := tokenize(part)
+		for i := 0; i < len(words); {
+			n, regions := g.match(words[i:])
+			if n == 0 {
+				i++
+				continue
+			}
+			mentions = append(mentions, pruneDescendants(regions))
+			i += n
+		}
+	}
+
+	region := g.resolve(mentions)
+	if region == nil {
+		return nil
+	}
+	return g.address(region)
+}
+
+// match returns the number of leading words forming the longest region name
+// and the regions of that name
+func (g *Gazetteer) match(words []string) (int, []*Region) {
+	for n := min(g.maxWords, len(words)); n > 0; n-- {
+		if regions, ok := g.byKey[strings.Join(words[:n], "")]; ok {
+			return n, regions
+		}
+	}
+	return 0, nil
+}
+
+// resolve picks the region the mentions agree on most
+func (g *Gazetteer) resolve(mentions [][]*Region) *Region {
+	var best []*Region
+	bestSupport := 0
+	seen := make(map[string]bool)
+
+	for _, mention := range mentions {
+		for _, candidate := range mention {
+			if seen[candidate.Code] {
+				continue
+			}
+			seen[candidate.Code] = true
+
+			// Mentions of the candidate or a region containing it support it
+			support := 0
+			for _, other := range mentions {
+				if slices.ContainsFunc(other, func(r *Region) bool { return isAncestorOrSelf(r, candidate) }) {
+					support++
+				}
+			}
+
+			switch {
+			case support > bestSupport:
+				best, bestSupport = []*Region{candidate}, support
+			case support == bestSupport:
+				best = append(best, candidate)
+			}
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+
+	deepest := best[0]
+	for _, r := range best[1:] {
+		if r.Level > deepest.Level {
+			deepest = r
+		}
+	}
+	if !slices.ContainsFunc(best, func(r *Region) bool { return !isAncestorOrSelf(r, deepest) }) {
+		return deepest
+	}
+
+	// The contenders disagree; fall back to the region containing them all
+	code := best[0].Code
+	for _, r := range best[1:] {
+		for code != "" && code != r.Code && !strings.HasPrefix(r.Code, code+".") {
+			code = parentCode(code)
+		}
+	}
+	return g.byCode[code]
+}
+
+// address fills an address with region and the regions containing it
+func (g *Gazetteer) address(region *Region) *model.Address {
+	addr := &model.Address{}
+	for r := region; r != nil; r = g.byCode[parentCode(r.Code)] {
+		switch r.Level {
+		case LevelProvince:
+			addr.ProvinceCode, addr.Province = r.Code, r.Name
+		case LevelCity:
+			addr.CityCode, addr.City = r.Code, r.Name
+		case LevelDistrict:
+			addr.DistrictCode, addr.District = r.Code, r.Name
+		case LevelVillage:
+			addr.VillageCode, addr.Village = r.Code, r.Name
+		}
+	}
+	return addr
+}
+
+// pruneDescendants drops the regions contained by another region of the
+// same name: "Setiabudi" names the kecamatan rather than its kelurahan
+func pruneDescendants(regions []*Region) []*Region {
+	if len(regions) < 2 {
+		return regions
+	}
+
+	kept := make([]*Region, 0, len(regions))
+	for _, r := range regions {
+		contained := slices.ContainsFunc(regions, func(o *Region) bool {
+			return o != r && isAncestorOrSelf(o, r)
+		})
+		if !contained {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// splitParts splits a location at the separators between its components
+func splitParts(location string) []string {
+	return strings.FieldsFunc(strings.ReplaceAll(location, " - ", ","), func(r rune) bool {
+		return strings.ContainsRune(",;/|()", r)
+	})
+}
+
+// tokenize lowercases s, splits it into words and expands abbreviations
+func tokenize(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if full, ok := abbreviations[w]; ok {
+			words[i] = full
+		}
+	}
+	return words
+}
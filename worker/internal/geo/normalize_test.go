@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	g := Default()
+
+	tests := []struct {
+		location string
+		want     string // deepest resolved code, empty for none
+	}{
+		// Full hierarchies
+		{location: "Kebayoran Baru, Jakarta Selatan", want: "31.71.01"},
+		{location: "Senayan, Kebayoran Baru, Jakarta Selatan, DKI Jakarta", want: "31.71.01.1010"},
+		{location: "Tebet Barat, Tebet", want: "31.71.09.1001"},
+		{location: "Serpong, Tangerang Selatan, Banten", want: "36.74.01"},
+
+		// Abbreviations and spelling variants
+		{location: "Kebayoran Baru, Jaksel", want: "31.71.01"},
+		{location: "Kby Baru, Jak-Sel", want: "31.71.01"},
+		{location: "Setia Budi, Jakarta Selatan", want: "31.71.10"},
+		{location: "Pal Merah, Jakbar", want: "31.74.07"},
+		{location: "Klp. Gading, Jakut", want: "31.75.06"},
+		{location: "Kab. Bogor", want: "32.01"},
+		{location: "Kabupaten Bekasi, Jawa Barat", want: "32.16"},
+		{location: "Kota Bekasi", want: "32.75"},
+		{location: "Kec. Tebet, Kota Adm. Jakarta Selatan", want: "31.71.09"},
+		{location: "Jakarta Selatan Kebayoran Baru", want: "31.71.01"},
+		{location: "Tangsel", want: "36.74"},
+
+		// Ambiguous names
+		{location: "Cipayung, Jakarta Timur", want: "31.72.09"},
+		{location: "Cipayung, Depok", want: "32.76.07"},
+		{location: "Cipayung", want: ""},
+		{location: "Bogor", want: "32"},
+		{location: "Bogor, Jawa Barat", want: "32"},
+		{location: "Pesanggrahan, Jakarta Selatan", want: "31.71.03"},
+		{location: "Gunung, Jakarta Pusat", want: "31"},
+
+		// Nothing known
+		{location: "", want: ""},
+		{location: "Lokasi strategis", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			addr := g.Normalize(tt.location)
+			got := ""
+			if addr != nil {
+				for _, code := range []string{addr.VillageCode, addr.DistrictCode, addr.CityCode, addr.ProvinceCode} {
+					if code != "" {
+						got = code
+						break
+					}
+				}
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q (%+v)", tt.want, got, addr)
+			}
+		})
+	}
+}
+
+func TestNormalizeFillsHierarchy(t *testing.T) {
+	addr := Default().Normalize("Senayan, Jaksel")
+	if addr == nil {
+		t.Fatal("expected an address")
+	}
+	if addr.Province != "DKI Jakarta" || addr.CityCode != "31.71" || addr.City != "Jakarta Selatan" ||
+		addr.District != "Kebayoran Baru" || addr.Village != "Senayan" {
+		t.Fatalf("unexpected address %+v", addr)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr bool
+	}{
+		{name: "valid", csv: "code,name,aliases\n31,DKI Jakarta,Jakarta\n31.71,Jakarta Selatan,Jaksel\n"},
+		{name: "unknown parent", csv: "code,name,aliases\n31.71,Jakarta Selatan,\n", wantErr: true},
+		{name: "duplicate code", csv: "code,name,aliases\n31,DKI Jakarta,\n31,Jakarta,\n", wantErr: true},
+		{name: "missing column", csv: "code,name,aliases\n31,DKI Jakarta\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+// Address is a listing location resolved to Indonesia's administrative
+// hierarchy: provinsi, kota/kabupaten, kecamatan and kelurahan/desa. Codes
+// are the Kemendagri region codes, e.g. "31.71.01"; the levels below the
+// deepest one the location names are empty.
+type Address struct {
+	ProvinceCode string `json:"province_code" bson:"province_code"`
+	Province     string `json:"province" bson:"province"`
+	CityCode     string `json:"city_code,omitempty" bson:"city_code,omitempty"`
+	City         string `json:"city,omitempty" bson:"city,omitempty"`
+	DistrictCode string `json:"district_code,omitempty" bson:"district_code,omitempty"`
+	District     string `json:"district,omitempty" bson:"district,omitempty"`
+	VillageCode  string `json:"village_code,omitempty" bson:"village_code,omitempty"`
+	Village      string `json:"village,omitempty" bson:"village,omitempty"`
+}
@@ -2,16 +2,62 @@ package model
 
 import "time"
 
-// JobStatus represents the status of a scheduled job
+// JobStatus represents a scrape run; it is persisted in the scrape_runs collection
 type JobStatus struct {
-	JobID     string    `json:"job_id"`
-	SiteName  string    `json:"site_name"`
-	Status    string    `json:"status"` // running, completed, failed
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time,omitempty"`
-	Duration  float64   `json:"duration_seconds"`
-	Scraped   int       `json:"scraped_count"`
-	Saved     int       `json:"saved_count"`
-	Errors    int       `json:"error_count"`
-	Message   string    `json:"message,omitempty"`
+	JobID        string      `json:"job_id" bson:"_id"`
+	SiteName     string      `json:"site_name" bson:"site_name"`
+	URL          string      `json:"url" bson:"url"`
+	Trigger      string      `json:"trigger" bson:"trigger"`
+	Attempt      int         `json:"attempt" bson:"attempt"`
+	RetryOf      string      `json:"retry_of,omitempty" bson:"retry_of,omitempty"`
+	WorkerID     string      `json:"worker_id,omitempty" bson:"worker_id,omitempty"`
+	FenceToken   int64       `json:"fence_token,omitempty" bson:"fence_token,omitempty"`
+	Status       string      `json:"status" bson:"status"`
+	QueuedAt     time.Time   `json:"queued_at,omitempty" bson:"queued_at,omitempty"`
+	StartTime    time.Time   `json:"start_time" bson:"start_time"`
+	EndTime      time.Time   `json:"end_time,omitempty" bson:"end_time,omitempty"`
+	Duration     float64     `json:"duration_seconds" bson:"duration_seconds"`
+	Scraped      int         `json:"scraped_count" bson:"scraped_count"`
+	Saved        int         `json:"saved_count" bson:"saved_count"`
+	Inserted     int         `json:"inserted_count" bson:"inserted_count"`
+	Updated      int         `json:"updated_count" bson:"updated_count"`
+	Unchanged    int         `json:"unchanged_count" bson:"unchanged_count"`
+	PriceChanged int         `json:"price_changed_count" bson:"price_changed_count"`
+	Errors       int         `json:"error_count" bson:"error_count"`
+	StaleWrites  int         `json:"stale_write_count" bson:"stale_write_count"`
+	Delisted     int         `json:"delisted_count" bson:"delisted_count"`
+	Message      string      `json:"message,omitempty" bson:"message,omitempty"`
+	Pages        []PageStats `json:"pages,omitempty" bson:"pages,omitempty"`
+}
+
+// Job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+
+	// JobStatusCancelled marks a run stopped on request and
+	// JobStatusInterrupted one stopped by a worker shutdown
+	JobStatusCancelled   = "cancelled"
+	JobStatusInterrupted = "interrupted"
+)
+
+// Job triggers
+const (
+	JobTriggerScheduled = "scheduled"
+	JobTriggerManual    = "manual"
+	JobTriggerCatchUp   = "catch_up"
+	JobTriggerRetry     = "retry"
+)
+
+// PageStats holds the outcome of a single crawled result page
+type PageStats struct {
+	Page     int     `json:"page" bson:"page"`
+	URL      string  `json:"url" bson:"url"`
+	Scraped  int     `json:"scraped_count" bson:"scraped_count"`
+	Saved    int     `json:"saved_count" bson:"saved_count"`
+	Enriched int     `json:"enriched_count" bson:"enriched_count"`
+	Errors   int     `json:"error_count" bson:"error_count"`
+	Duration float64 `json:"duration_seconds" bson:"duration_seconds"`
 }
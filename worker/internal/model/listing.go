@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // Listing represents a house listing scraped from a website
 type Listing struct {
@@ -8,7 +11,7 @@ type Listing struct {
 	SiteName     string    `json:"site_name" bson:"site_name" validate:"required"`
 	URL          string    `json:"url" bson:"url" validate:"required,url"`
 	Title        string    `json:"title" bson:"title" validate:"required"`
-	Price        float64   `json:"price" bson:"price" validate:"required,gt=0"`
+	Price        float64   `json:"price" bson:"price" validate:"min=0"`
 	Location     string    `json:"location" bson:"location" validate:"required"`
 	Bedrooms     int       `json:"bedrooms" bson:"bedrooms" validate:"min=0"`
 	Bathrooms    int       `json:"bathrooms" bson:"bathrooms" validate:"min=0"`
@@ -21,4 +24,134 @@ type Listing struct {
 	ScrapedAt    time.Time `json:"scraped_at" bson:"scraped_at"`
 	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
 	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+
+	// PriceInfo is the structured price Price was taken from. Price is zero
+	// for a negotiable price without an amount ("Harga Nego").
+	PriceInfo *Price `json:"price_info,omitempty" bson:"price_info,omitempty"`
+
+	// ListingType tells sale from rent listings; Price of a rent listing is
+	// the rent per RentPeriod. Listings stored without a type are for sale.
+	ListingType string `json:"listing_type" bson:"listing_type"`
+	RentPeriod  string `json:"rent_period,omitempty" bson:"rent_period,omitempty"`
+
+	// PropertyType is one of PropertyTypes, or empty if it could not be told;
+	// the confidence ranges from 0 to 1
+	PropertyType           string  `json:"property_type" bson:"property_type"`
+	PropertyTypeConfidence float64 `json:"property_type_confidence" bson:"property_type_confidence"`
+
+	// Address is Location resolved to the administrative hierarchy; nil if
+	// it names no known region
+	Address *Address `json:"address,omitempty" bson:"address,omitempty"`
+
+	// Price tracking; set by the repository when a re-scrape sees a new price
+	PreviousPrice  float64    `json:"previous_price,omitempty" bson:"previous_price,omitempty"`
+	PriceChangedAt *time.Time `json:"price_changed_at,omitempty" bson:"price_changed_at,omitempty"`
+
+	// RunID is the scrape run that last saw this listing and FenceToken the
+	// lock fencing token that run held; writes with an older token are rejected
+	RunID      string `json:"run_id,omitempty" bson:"run_id,omitempty"`
+	FenceToken int64  `json:"-" bson:"fence_token,omitempty"`
+
+	// Lifecycle; a listing becomes inactive after missing several full crawls
+	Status     string     `json:"status" bson:"status"`
+	MissedRuns int        `json:"missed_runs" bson:"missed_runs"`
+	DelistedAt *time.Time `json:"delisted_at,omitempty" bson:"delisted_at,omitempty"`
+
+	// DaysOnMarket is derived from CreatedAt and DelistedAt and never stored
+	DaysOnMarket int `json:"days_on_market" bson:"-"`
+}
+
+// Listing statuses
+const (
+	ListingStatusActive   = "active"
+	ListingStatusInactive = "inactive"
+)
+
+// Listing types
+const (
+	ListingTypeSale = "sale"
+	ListingTypeRent = "rent"
+)
+
+// Property types
+const (
+	PropertyTypeHouse     = "rumah"
+	PropertyTypeApartment = "apartemen"
+	PropertyTypeLand      = "tanah"
+	PropertyTypeShophouse = "ruko"
+	PropertyTypeWarehouse = "gudang"
+)
+
+// PropertyTypes lists the property types a listing can have
+var PropertyTypes = []string{
+	PropertyTypeHouse,
+	PropertyTypeApartment,
+	PropertyTypeLand,
+	PropertyTypeShophouse,
+	PropertyTypeWarehouse,
+}
+
+// Type returns the listing type, treating listings stored before types were
+// recorded as sale listings
+func (l *Listing) Type() string {
+	if l.ListingType == "" {
+		return ListingTypeSale
+	}
+	return l.ListingType
+}
+
+// SamePriceBasis reports whether the prices of two listings can be compared:
+// both are for sale, or both are rented out per the same period
+func (l *Listing) SamePriceBasis(o *Listing) bool {
+	return l.Type() == o.Type() && l.RentPeriod == o.RentPeriod
+}
+
+// ComputeDaysOnMarket sets DaysOnMarket from the first time the listing was
+// seen until it was delisted, or until now for active listings
+func (l *Listing) ComputeDaysOnMarket(now time.Time) {
+	if l.CreatedAt.IsZero() {
+		l.DaysOnMarket = 0
+		return
+	}
+
+	end := now
+	if l.DelistedAt != nil {
+		end = *l.DelistedAt
+	}
+	l.DaysOnMarket = int(end.Sub(l.CreatedAt).Hours() / 24)
+}
+
+// SameContent reports whether two listings carry the same scraped content,
+// ignoring bookkeeping fields such as timestamps, run and lifecycle state
+func (l *Listing) SameContent(o *Listing) bool {
+	return l.Title == o.Title &&
+		l.Price == o.Price &&
+		samePrice(l.PriceInfo, o.PriceInfo) &&
+		l.SamePriceBasis(o) &&
+		l.PropertyType == o.PropertyType &&
+		l.PropertyTypeConfidence == o.PropertyTypeConfidence &&
+		l.Location == o.Location &&
+		sameAddress(l.Address, o.Address) &&
+		l.Bedrooms == o.Bedrooms &&
+		l.Bathrooms == o.Bathrooms &&
+		l.LandArea == o.LandArea &&
+		l.BuildingArea == o.BuildingArea &&
+		l.Description == o.Description &&
+		l.AgentName == o.AgentName &&
+		l.AgentPhone == o.AgentPhone &&
+		slices.Equal(l.Images, o.Images)
+}
+
+func samePrice(a, b *Price) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameAddress(a, b *Address) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
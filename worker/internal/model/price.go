@@ -0,0 +1,22 @@
+package model
+
+// Price is a listing price as the portal advertises it
+type Price struct {
+	// Amount is the sale price or the rent per Period in rupiah; for a price
+	// range it is the lower bound
+	Amount float64 `json:"amount" bson:"amount"`
+	Period string  `json:"period" bson:"period"`
+
+	Negotiable bool `json:"negotiable" bson:"negotiable"`
+
+	// Min and Max bound an advertised price range, e.g. of a new development
+	Min float64 `json:"min,omitempty" bson:"min,omitempty"`
+	Max float64 `json:"max,omitempty" bson:"max,omitempty"`
+}
+
+// Price periods; a sale price has no recurring period
+const (
+	PricePeriodSale    = "sale"
+	PricePeriodMonthly = "monthly"
+	PricePeriodYearly  = "yearly"
+)
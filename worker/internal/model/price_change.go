@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PriceChange records a price change observed when a listing is re-scraped
+type PriceChange struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	ListingID string    `json:"listing_id" bson:"listing_id"`
+	URL       string    `json:"url" bson:"url"`
+	SiteName  string    `json:"site_name" bson:"site_name"`
+	OldPrice  float64   `json:"old_price" bson:"old_price"`
+	NewPrice  float64   `json:"new_price" bson:"new_price"`
+	ChangedAt time.Time `json:"changed_at" bson:"changed_at"`
+	RunID     string    `json:"run_id,omitempty" bson:"run_id,omitempty"`
+}
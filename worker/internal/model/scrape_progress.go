@@ -0,0 +1,11 @@
+package model
+
+// ScrapeProgress is a progress event emitted while a page is being scraped
+type ScrapeProgress struct {
+	SiteName  string `json:"site_name"`
+	URL       string `json:"url"`
+	Scraped   int    `json:"scraped_count"`
+	Errors    int    `json:"error_count"`
+	LastError string `json:"last_error,omitempty"`
+	Done      bool   `json:"done"`
+}
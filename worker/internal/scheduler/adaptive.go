@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
+)
+
+const (
+	// defaultChurnRuns is the number of completed runs churn is measured over
+	defaultChurnRuns = 5
+
+	// defaultTargetChurn is the churn that gets a site its min interval
+	defaultTargetChurn = 0.1
+
+	// adaptiveSlack lets a schedule slot count as due when the last run
+	// started a little later than its own slot
+	adaptiveSlack = time.Minute
+)
+
+// AdaptivePolicy bounds the interval between runs of a site that follows
+// its churn
+type AdaptivePolicy struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	Runs        int
+	TargetChurn float64
+}
+
+func (p AdaptivePolicy) enabled() bool {
+	return p.MaxInterval > 0
+}
+
+// interval maps churn onto the policy's bounds: the max interval without
+// churn, the min interval at or above the target churn and linear in between
+func (p AdaptivePolicy) interval(churn float64) time.Duration {
+	target := p.TargetChurn
+	if target <= 0 {
+		target = defaultTargetChurn
+	}
+
+	f := min(max(churn/target, 0), 1)
+	d := p.MaxInterval - time.Duration(f*float64(p.MaxInterval-p.MinInterval))
+	return d.Round(time.Second)
+}
+
+func (p AdaptivePolicy) runs() int {
+	if p.Runs <= 0 {
+		return defaultChurnRuns
+	}
+	return p.Runs
+}
+
+// churn returns the share of listings saved by runs that were new or changed
+// price, and the number of runs that saved anything
+func churn(runs []*model.JobStatus) (float64, int) {
+	var changed, saved, counted int
+	for _, run := range runs {
+		if run.Saved == 0 {
+			continue
+		}
+		changed += run.Inserted + run.PriceChanged
+		saved += run.Saved
+		counted++
+	}
+	if saved == 0 {
+		return 0, 0
+	}
+	return float64(changed) / float64(saved), counted
+}
+
+// adaptiveState is the effective interval of an adaptive site on this worker
+type adaptiveState struct {
+	interval  time.Duration
+	churn     float64
+	runs      int
+	lastRun   time.Time
+	updatedAt time.Time
+}
+
+// AdaptiveInfo reports the effective interval of an adaptive schedule
+type AdaptiveInfo struct {
+	Interval    float64    `json:"interval_seconds"`
+	MinInterval float64    `json:"min_interval_seconds"`
+	MaxInterval float64    `json:"max_interval_seconds"`
+	Churn       float64    `json:"churn"`
+	Runs        int        `json:"runs"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at,omitzero"`
+}
+
+// updateAdaptive recomputes the effective interval of a site from its recent
+// completed runs. A site without runs that saved anything gets the min
+// interval until there is churn to go by.
+func (s *Scheduler) updateAdaptive(ctx context.Context, siteName string, policy AdaptivePolicy) *adaptiveState {
+	runs, err := s.service.ListJobs(ctx, &storage.RunFilter{
+		SiteName: siteName,
+		Status:   model.JobStatusCompleted,
+		Limit:    policy.runs(),
+	})
+	if err != nil {
+		s.logger.Warn("failed to load runs for churn", zap.String("site", siteName), zap.Error(err))
+	}
+
+	c, n := churn(runs)
+	st := &adaptiveState{
+		interval:  policy.MinInterval,
+		churn:     c,
+		runs:      n,
+		updatedAt: time.Now(),
+	}
+	if n > 0 {
+		st.interval = policy.interval(c)
+	}
+
+	s.mu.Lock()
+	prev, ok := s.adaptive[siteName]
+	if ok {
+		st.lastRun = prev.lastRun
+	}
+	s.adaptive[siteName] = st
+	s.mu.Unlock()
+
+	if ok && prev.interval == st.interval {
+		return st
+	}
+	s.logger.Info("crawl interval updated",
+		zap.String("site", siteName),
+		zap.Float64("churn", c),
+		zap.Int("runs", n),
+		zap.Duration("interval", st.interval))
+	return st
+}
+
+// loadAdaptive computes the effective interval of every adaptive site
+func (s *Scheduler) loadAdaptive(ctx context.Context) {
+	s.mu.Lock()
+	policies := make(map[string]AdaptivePolicy)
+	for name, opts := range s.sites {
+		if opts.Adaptive.enabled() {
+			policies[name] = opts.Adaptive
+		}
+	}
+	s.mu.Unlock()
+
+	for name, policy := range policies {
+		st := s.updateAdaptive(ctx, name, policy)
+		if last, err := s.lastSuccess(ctx, name); err == nil {
+			s.mu.Lock()
+			st.lastRun = last
+			s.mu.Unlock()
+		}
+	}
+}
+
+// adaptiveDue reports whether the effective interval of an adaptive site has
+// passed since its last successful scheduled run. The interval is recomputed
+// first, since the last run may have finished on another worker.
+func (s *Scheduler) adaptiveDue(ctx context.Context, siteName string, opts SiteOptions, now time.Time) bool {
+	st := s.updateAdaptive(ctx, siteName, opts.Adaptive)
+
+	last, err := s.lastSuccess(ctx, siteName)
+	if err != nil {
+		s.logger.Warn("failed to load last run", zap.String("site", siteName), zap.Error(err))
+		return true
+	}
+
+	s.mu.Lock()
+	st.lastRun = last
+	s.mu.Unlock()
+
+	if last.IsZero() {
+		return true
+	}
+	return !now.Add(opts.Jitter + adaptiveSlack).Before(last.Add(st.interval))
+}
+
+// noteAdaptiveRun notes a successful scheduled run of an adaptive site and
+// recomputes its interval
+func (s *Scheduler) noteAdaptiveRun(ctx context.Context, siteName string, startedAt time.Time) {
+	opts := s.siteOptions(siteName)
+	if !opts.Adaptive.enabled() {
+		return
+	}
+
+	st := s.updateAdaptive(ctx, siteName, opts.Adaptive)
+	s.mu.Lock()
+	st.lastRun = startedAt
+	s.mu.Unlock()
+}
+
+// adaptiveInfoLocked reports the effective interval of an adaptive site and
+// adjusts next to the first slot at which it is due. The caller holds s.mu.
+func (s *Scheduler) adaptiveInfoLocked(siteName string, next func(time.Time) time.Time) (*AdaptiveInfo, *time.Time) {
+	opts := s.sites[siteName]
+	if !opts.Adaptive.enabled() {
+		return nil, nil
+	}
+
+	info := &AdaptiveInfo{
+		Interval:    opts.Adaptive.MinInterval.Seconds(),
+		MinInterval: opts.Adaptive.MinInterval.Seconds(),
+		MaxInterval: opts.Adaptive.MaxInterval.Seconds(),
+	}
+
+	st, ok := s.adaptive[siteName]
+	if !ok {
+		return info, nil
+	}
+	info.Interval = st.interval.Seconds()
+	info.Churn = st.churn
+	info.Runs = st.runs
+	info.UpdatedAt = st.updatedAt
+	if st.lastRun.IsZero() || next == nil {
+		return info, nil
+	}
+
+	last := st.lastRun
+	info.LastRun = &last
+
+	due := last.Add(st.interval - opts.Jitter - adaptiveSlack)
+	from := time.Now()
+	if due.After(from) {
+		from = due.Add(-time.Second)
+	}
+	n := next(from)
+	return info, &n
+}
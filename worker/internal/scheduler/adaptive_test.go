@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestChurn(t *testing.T) {
+	runs := []*model.JobStatus{
+		{Saved: 100, Inserted: 5, PriceChanged: 5},
+		{Saved: 0},
+		{Saved: 100, Inserted: 10},
+	}
+
+	got, n := churn(runs)
+	if n != 2 {
+		t.Fatalf("expected 2 counted runs, got %d", n)
+	}
+	if got != 0.1 {
+		t.Fatalf("expected churn 0.1, got %v", got)
+	}
+
+	if got, n := churn(nil); got != 0 || n != 0 {
+		t.Fatalf("expected no churn without runs, got %v over %d", got, n)
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	policy := AdaptivePolicy{MinInterval: time.Hour, MaxInterval: 25 * time.Hour, TargetChurn: 0.2}
+
+	tests := []struct {
+		churn float64
+		want  time.Duration
+	}{
+		{churn: 0, want: 25 * time.Hour},
+		{churn: 0.05, want: 19 * time.Hour},
+		{churn: 0.1, want: 13 * time.Hour},
+		{churn: 0.2, want: time.Hour},
+		{churn: 0.9, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := policy.interval(tt.churn); got != tt.want {
+			t.Errorf("churn %v: expected %v, got %v", tt.churn, tt.want, got)
+		}
+	}
+}
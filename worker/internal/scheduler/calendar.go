@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/service"
+)
+
+// holidayLayout is the date format of the holiday list
+const holidayLayout = "2006-01-02"
+
+// ErrQuietHours refuses runs of a site that must not be crawled now
+var ErrQuietHours = errors.New("site is in quiet hours")
+
+// errQuietHoursCancel stops runs that reach a site's quiet hours; they are
+// recorded as cancelled
+var errQuietHoursCancel = fmt.Errorf("%w: %w", service.ErrJobCancelled, ErrQuietHours)
+
+// timeWindow is a daily window in minutes since midnight; a window whose end
+// is before its start wraps midnight
+type timeWindow struct {
+	start int
+	end   int
+}
+
+// parseTimeWindow parses "HH:MM-HH:MM"
+func parseTimeWindow(v string) (timeWindow, error) {
+	from, to, ok := strings.Cut(v, "-")
+	if !ok {
+		return timeWindow{}, fmt.Errorf("quiet hours %q: expected HH:MM-HH:MM", v)
+	}
+
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return timeWindow{}, fmt.Errorf("quiet hours %q: %w", v, err)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return timeWindow{}, fmt.Errorf("quiet hours %q: %w", v, err)
+	}
+
+	return timeWindow{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+	}, nil
+}
+
+func (w timeWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// quietAt reports whether a site with opts must not be crawled at t
+func (o SiteOptions) quietAt(t time.Time) bool {
+	t = t.In(o.location())
+	if o.holidays[t.Format(holidayLayout)] {
+		return true
+	}
+	for _, w := range o.quietHours {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o SiteOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+// parseSpec parses a schedule in the site's time zone. An explicit TZ= or
+// CRON_TZ= prefix in spec wins.
+func (o SiteOptions) parseSpec(spec string) (cron.Schedule, error) {
+	schedule, err := specParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	if o.Location != nil && !strings.HasPrefix(spec, "TZ=") && !strings.HasPrefix(spec, "CRON_TZ=") {
+		if s, ok := schedule.(*cron.SpecSchedule); ok {
+			s.Location = o.Location
+		}
+	}
+	return schedule, nil
+}
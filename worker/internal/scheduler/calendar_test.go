@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+)
+
+func TestSiteOptionsQuietAt(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	opts, err := SiteOptionsFromConfig(&config.SiteConfig{
+		Name:         "testsite",
+		Timezone:     "Asia/Jakarta",
+		QuietHours:   []string{"22:00-06:00", "12:00-13:00"},
+		SkipHolidays: true,
+	}, config.SchedulerConfig{Holidays: []string{"2026-08-17"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, jakarta)
+	}
+
+	tests := []struct {
+		name  string
+		t     time.Time
+		quiet bool
+	}{
+		{name: "daytime", t: at(8, 10, 9, 0), quiet: false},
+		{name: "late evening", t: at(8, 10, 23, 30), quiet: true},
+		{name: "early morning", t: at(8, 10, 5, 59), quiet: true},
+		{name: "window end is exclusive", t: at(8, 10, 6, 0), quiet: false},
+		{name: "lunch window", t: at(8, 10, 12, 30), quiet: true},
+		{name: "holiday", t: at(8, 17, 9, 0), quiet: true},
+		{name: "utc time in jakarta quiet hours", t: time.Date(2026, 8, 10, 16, 0, 0, 0, time.UTC), quiet: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := opts.quietAt(tt.t); got != tt.quiet {
+				t.Fatalf("expected quiet=%v at %v, got %v", tt.quiet, tt.t, got)
+			}
+		})
+	}
+}
+
+func TestSiteOptionsFromConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		site config.SiteConfig
+	}{
+		{name: "bad quiet hours", site: config.SiteConfig{QuietHours: []string{"22:00"}}},
+		{name: "bad clock", site: config.SiteConfig{QuietHours: []string{"25:00-06:00"}}},
+		{name: "bad time zone", site: config.SiteConfig{Timezone: "Mars/Base"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SiteOptionsFromConfig(&tt.site, config.SchedulerConfig{}); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSiteOptionsParseSpec_UsesTimezone(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	opts := SiteOptions{Location: jakarta}
+
+	schedule, err := opts.parseSpec("0 0 2 * * *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	next := schedule.Next(time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 8, 10, 19, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("expected next run at %v, got %v", want, next.UTC())
+	}
+
+	// An explicit zone in the spec wins
+	schedule, err = opts.parseSpec("CRON_TZ=UTC 0 0 2 * * *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next = schedule.Next(time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 8, 10, 2, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("expected next run at %v, got %v", want, next.UTC())
+	}
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/service"
+)
+
+// Redis keys of cancel requests. The flag is the durable request, checked
+// before a queued job starts and on every lock renewal; the channel makes
+// the worker running the job stop right away.
+const (
+	cancelKeyPrefix = "scrape:cancel:"
+	cancelChannel   = "scrape:cancel"
+)
+
+// cancelFlagTTL keeps a cancel request around for a job that is still queued
+const cancelFlagTTL = 24 * time.Hour
+
+// ErrMaxDuration cancels a job that ran longer than its site's max duration
+var ErrMaxDuration = errors.New("max duration exceeded")
+
+// CancelJob asks whichever worker runs or will run the job to stop it. The
+// job is recorded as cancelled once it has stopped.
+func (s *Scheduler) CancelJob(ctx context.Context, jobID string) error {
+	if err := s.redis.Set(ctx, cancelKeyPrefix+jobID, s.workerID, cancelFlagTTL).Err(); err != nil {
+		return fmt.Errorf("requesting cancel: %w", err)
+	}
+
+	s.cancelLocal(jobID)
+	if err := s.redis.Publish(ctx, cancelChannel, jobID).Err(); err != nil {
+		s.logger.Error("failed to publish cancel request",
+			zap.String("job_id", jobID),
+			zap.Error(err))
+	}
+
+	s.logger.Info("job cancel requested", zap.String("job_id", jobID))
+	return nil
+}
+
+// cancelRequested reports whether a cancel request for the job is pending
+func (s *Scheduler) cancelRequested(ctx context.Context, jobID string) bool {
+	n, err := s.redis.Exists(ctx, cancelKeyPrefix+jobID).Result()
+	if err != nil {
+		s.logger.Warn("failed to check cancel request",
+			zap.String("job_id", jobID),
+			zap.Error(err))
+		return false
+	}
+	return n > 0
+}
+
+// cancelLocal cancels the job if it runs on this worker
+func (s *Scheduler) cancelLocal(jobID string) {
+	s.mu.Lock()
+	rj, ok := s.running[jobID]
+	s.mu.Unlock()
+
+	if ok {
+		rj.cancel(service.ErrJobCancelled)
+		s.logger.Info("job cancelled", zap.String("job_id", jobID))
+	}
+}
+
+// runningJob is a job running on this worker
+type runningJob struct {
+	info   RunningJob
+	cancel context.CancelCauseFunc
+}
+
+func (s *Scheduler) trackJob(job *model.JobStatus, token int64, cancel context.CancelCauseFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[job.JobID] = &runningJob{
+		info: RunningJob{
+			JobID:      job.JobID,
+			SiteName:   job.SiteName,
+			Trigger:    job.Trigger,
+			FenceToken: token,
+			StartedAt:  time.Now(),
+		},
+		cancel: cancel,
+	}
+}
+
+func (s *Scheduler) untrackJob(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, jobID)
+}
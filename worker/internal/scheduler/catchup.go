@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// Redis keys of the catch-up state. The last success key holds the unix
+// start time of a site's last successful scheduled run; the claim key makes
+// sure a missed run is caught up by a single worker.
+const (
+	lastSuccessKeyPrefix = "scheduler:last_success:"
+	catchUpKeyPrefix     = "scheduler:catchup:"
+)
+
+// maxMissedRuns bounds the search for the latest missed run of a schedule
+// that fires very often
+const maxMissedRuns = 10000
+
+// recordSuccess stores the start time of a successful scheduled run
+func (s *Scheduler) recordSuccess(ctx context.Context, siteName string, startedAt time.Time) {
+	if err := s.redis.Set(ctx, lastSuccessKeyPrefix+siteName, startedAt.Unix(), 0).Err(); err != nil {
+		s.logger.Error("failed to record last successful run",
+			zap.String("site", siteName),
+			zap.Error(err))
+	}
+}
+
+// lastSuccess returns the start time of the last successful scheduled run,
+// or the zero time if none was recorded
+func (s *Scheduler) lastSuccess(ctx context.Context, siteName string) (time.Time, error) {
+	v, err := s.redis.Get(ctx, lastSuccessKeyPrefix+siteName).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("loading last successful run: %w", err)
+	}
+
+	unix, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decoding last successful run: %w", err)
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// catchUpMissed runs, once across the cluster, every schedule that should
+// have fired since its last successful run and was missed no longer than the
+// catch-up window ago. Sites without a recorded success are left alone. With
+// leader election the leader pushes the missed runs onto the shared queue.
+func (s *Scheduler) catchUpMissed(ctx context.Context) {
+	if s.catchUp <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	schedules := make([]Schedule, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if !job.schedule.Paused {
+			schedules = append(schedules, job.schedule)
+		}
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	for _, sch := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+
+		missed, err := s.missedRun(ctx, sch, now)
+		if err != nil {
+			s.logger.Error("failed to check missed run", zap.String("site", sch.SiteName), zap.Error(err))
+			continue
+		}
+		if missed.IsZero() {
+			continue
+		}
+		if s.siteOptions(sch.SiteName).quietAt(now) {
+			s.logger.Info("not catching up missed run in quiet hours", zap.String("site", sch.SiteName))
+			continue
+		}
+
+		claimed, err := s.redis.SetNX(ctx,
+			fmt.Sprintf("%s%s:%d", catchUpKeyPrefix, sch.SiteName, missed.Unix()),
+			s.workerID, s.catchUp,
+		).Result()
+		if err != nil {
+			s.logger.Error("failed to claim missed run", zap.String("site", sch.SiteName), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		s.logger.Info("catching up missed run",
+			zap.String("site", sch.SiteName),
+			zap.Time("missed", missed))
+
+		job := s.service.NewJob(sch.SiteName, sch.URL, model.JobTriggerCatchUp)
+		if s.election {
+			s.dispatch(ctx, job)
+			continue
+		}
+		s.exec.submit(ctx, sch.SiteName, job.Trigger, time.Now(), func(ctx context.Context) {
+			if _, err := s.runLocked(ctx, job, nil); err != nil {
+				s.logger.Error("failed to acquire lock",
+					zap.String("site", sch.SiteName),
+					zap.Error(err))
+			}
+		})
+	}
+}
+
+// missedRun returns the latest time sch should have fired between its last
+// successful run and now, if that lies within the catch-up window
+func (s *Scheduler) missedRun(ctx context.Context, sch Schedule, now time.Time) (time.Time, error) {
+	last, err := s.lastSuccess(ctx, sch.SiteName)
+	if err != nil || last.IsZero() {
+		return time.Time{}, err
+	}
+
+	spec, err := s.siteOptions(sch.SiteName).parseSpec(sch.Spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return latestMissed(spec, last, now, s.catchUp), nil
+}
+
+// latestMissed returns the latest activation of spec after last and not after
+// now, or the zero time if there is none or it is older than window
+func latestMissed(spec cron.Schedule, last, now time.Time, window time.Duration) time.Time {
+	var missed time.Time
+	for t, i := spec.Next(last), 0; !t.IsZero() && !t.After(now) && i < maxMissedRuns; t, i = spec.Next(t), i+1 {
+		missed = t
+	}
+
+	if missed.IsZero() || now.Sub(missed) > window {
+		return time.Time{}
+	}
+	return missed
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatestMissed(t *testing.T) {
+	daily, err := specParser.Parse("0 0 2 * * *")
+	if err != nil {
+		t.Fatalf("parsing spec: %v", err)
+	}
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name   string
+		last   time.Time
+		now    time.Time
+		window time.Duration
+		want   time.Time
+	}{
+		{name: "nothing missed", last: at(10, 2, 0), now: at(10, 23, 0), window: 6 * time.Hour},
+		{name: "missed within window", last: at(10, 2, 0), now: at(11, 4, 0), window: 6 * time.Hour, want: at(11, 2, 0)},
+		{name: "missed outside window", last: at(10, 2, 0), now: at(11, 9, 0), window: 6 * time.Hour},
+		{name: "several missed, latest counts", last: at(7, 2, 0), now: at(11, 3, 0), window: 6 * time.Hour, want: at(11, 2, 0)},
+		{name: "exactly at activation", last: at(10, 2, 0), now: at(11, 2, 0), window: time.Hour, want: at(11, 2, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := latestMissed(daily, tt.last, tt.now, tt.window)
+			if !got.Equal(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+const (
+	// defaultPoolSize is the number of runs a worker executes at once when
+	// scheduler.workers is not set
+	defaultPoolSize = 2
+
+	// waitSamples is the number of recently started runs the wait time
+	// statistics cover
+	waitSamples = 100
+)
+
+// Run priorities. A free slot goes to the waiting run with the highest
+// priority, the one waiting longest first among equals.
+const (
+	priorityScheduled = iota
+	priorityRetry
+	priorityManual
+)
+
+// triggerPriority returns the priority of a run with the given trigger
+func triggerPriority(trigger string) int {
+	switch trigger {
+	case model.JobTriggerManual:
+		return priorityManual
+	case model.JobTriggerRetry, model.JobTriggerCatchUp:
+		return priorityRetry
+	default:
+		return priorityScheduled
+	}
+}
+
+// execTask is a run waiting for or holding an executor slot. started is
+// closed when it gets a slot and done when it has finished or was dropped.
+type execTask struct {
+	ctx      context.Context
+	site     string
+	priority int
+	queuedAt time.Time
+	seq      uint64
+	run      func(ctx context.Context)
+
+	started chan struct{}
+	done    chan struct{}
+}
+
+// executor runs the scheduled, caught-up and queued runs of a worker on a
+// bounded pool, one run of each site at a time: the site lock admits no
+// second run anywhere in the cluster
+type executor struct {
+	size int
+
+	mu          sync.Mutex
+	pending     []*execTask
+	running     int
+	siteRunning map[string]int
+	seq         uint64
+	stopped     bool
+
+	// waits holds the wait times of the last waitSamples started runs,
+	// written round-robin at waitNext
+	waits    []time.Duration
+	waitNext int
+
+	wg sync.WaitGroup
+}
+
+func newExecutor(size int) *executor {
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	return &executor{
+		size:        size,
+		siteRunning: make(map[string]int),
+	}
+}
+
+// submit adds a run of site that has been waiting since queuedAt. It starts
+// as soon as a slot is free and no other run of the site is running; run
+// gets ctx.
+func (e *executor) submit(ctx context.Context, site, trigger string, queuedAt time.Time, run func(ctx context.Context)) *execTask {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.submitLocked(ctx, site, trigger, queuedAt, run)
+}
+
+// submitIfIdle submits a run like submit unless a run of site is already
+// running, in which case it returns nil
+func (e *executor) submitIfIdle(ctx context.Context, site, trigger string, queuedAt time.Time, run func(ctx context.Context)) *execTask {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.siteRunning[site] > 0 {
+		return nil
+	}
+	return e.submitLocked(ctx, site, trigger, queuedAt, run)
+}
+
+func (e *executor) submitLocked(ctx context.Context, site, trigger string, queuedAt time.Time, run func(ctx context.Context)) *execTask {
+	t := &execTask{
+		ctx:      ctx,
+		site:     site,
+		priority: triggerPriority(trigger),
+		queuedAt: queuedAt,
+		run:      run,
+		started:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if e.stopped {
+		close(t.done)
+		return t
+	}
+
+	e.seq++
+	t.seq = e.seq
+	e.pending = append(e.pending, t)
+	e.dispatchLocked()
+	return t
+}
+
+// stop drops the waiting runs and refuses new ones; running ones finish on
+// their own
+func (e *executor) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.stopped = true
+	for _, t := range e.pending {
+		close(t.done)
+	}
+	e.pending = nil
+}
+
+// wait blocks until every started run has finished
+func (e *executor) wait() {
+	e.wg.Wait()
+}
+
+// dispatchLocked starts waiting runs while slots are free
+func (e *executor) dispatchLocked() {
+	for e.running < e.size {
+		next := -1
+		for i, t := range e.pending {
+			if e.siteRunning[t.site] > 0 {
+				continue
+			}
+			if next < 0 || t.priority > e.pending[next].priority ||
+				(t.priority == e.pending[next].priority && t.seq < e.pending[next].seq) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return
+		}
+
+		t := e.pending[next]
+		e.pending = slices.Delete(e.pending, next, next+1)
+		e.start(t)
+	}
+}
+
+func (e *executor) start(t *execTask) {
+	e.running++
+	e.siteRunning[t.site]++
+	e.recordWait(time.Since(t.queuedAt))
+	e.wg.Add(1)
+	close(t.started)
+
+	go func() {
+		defer e.wg.Done()
+		defer close(t.done)
+		defer e.finish(t)
+		t.run(t.ctx)
+	}()
+}
+
+func (e *executor) finish(t *execTask) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.running--
+	if e.siteRunning[t.site]--; e.siteRunning[t.site] <= 0 {
+		delete(e.siteRunning, t.site)
+	}
+	if !e.stopped {
+		e.dispatchLocked()
+	}
+}
+
+func (e *executor) recordWait(d time.Duration) {
+	if len(e.waits) < waitSamples {
+		e.waits = append(e.waits, d)
+		return
+	}
+	e.waits[e.waitNext] = d
+	e.waitNext = (e.waitNext + 1) % waitSamples
+}
+
+// ExecutorStats describes the runs of a worker's executor and the shared queue
+type ExecutorStats struct {
+	WorkerID string `json:"worker_id"`
+	PoolSize int    `json:"pool_size"`
+	Running  int    `json:"running"`
+	Pending  int    `json:"pending"`
+
+	// QueueDepth is the number of queued runs no worker has taken yet and
+	// RetriesWaiting the number of retries that are not due yet
+	QueueDepth     int64 `json:"queue_depth"`
+	RetriesWaiting int64 `json:"retries_waiting"`
+
+	// Wait times from queueing or firing to start: the longest current wait
+	// and the average and maximum over recently started runs
+	OldestPendingWait float64 `json:"oldest_pending_wait_seconds"`
+	AvgWait           float64 `json:"avg_wait_seconds"`
+	MaxWait           float64 `json:"max_wait_seconds"`
+
+	Sites []SiteExecutorStats `json:"sites"`
+}
+
+// SiteExecutorStats describes the runs of one site on a worker
+type SiteExecutorStats struct {
+	SiteName string `json:"site_name"`
+	Running  int    `json:"running"`
+	Pending  int    `json:"pending"`
+}
+
+// stats returns the local part of the executor statistics
+func (e *executor) stats(now time.Time) ExecutorStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st := ExecutorStats{
+		PoolSize: e.size,
+		Running:  e.running,
+		Pending:  len(e.pending),
+	}
+
+	sites := make(map[string]*SiteExecutorStats)
+	site := func(name string) *SiteExecutorStats {
+		if ss, ok := sites[name]; ok {
+			return ss
+		}
+		ss := &SiteExecutorStats{SiteName: name}
+		sites[name] = ss
+		return ss
+	}
+	for name, n := range e.siteRunning {
+		site(name).Running = n
+	}
+	for _, t := range e.pending {
+		site(t.site).Pending++
+		st.OldestPendingWait = max(st.OldestPendingWait, now.Sub(t.queuedAt).Seconds())
+	}
+
+	var total time.Duration
+	for _, d := range e.waits {
+		total += d
+		st.MaxWait = max(st.MaxWait, d.Seconds())
+	}
+	if len(e.waits) > 0 {
+		st.AvgWait = (total / time.Duration(len(e.waits))).Seconds()
+	}
+
+	st.Sites = make([]SiteExecutorStats, 0, len(sites))
+	for _, ss := range sites {
+		st.Sites = append(st.Sites, *ss)
+	}
+	slices.SortFunc(st.Sites, func(a, b SiteExecutorStats) int {
+		return strings.Compare(a.SiteName, b.SiteName)
+	})
+
+	return st
+}
+
+// ExecutorStats returns the executor statistics of this worker together with
+// the depth of the shared queue
+func (s *Scheduler) ExecutorStats(ctx context.Context) (*ExecutorStats, error) {
+	st := s.exec.stats(time.Now())
+	st.WorkerID = s.workerID
+
+	depth, err := s.redis.LLen(ctx, queueKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("loading queue depth: %w", err)
+	}
+	st.QueueDepth = depth
+
+	retries, err := s.redis.ZCard(ctx, retriesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("loading waiting retries: %w", err)
+	}
+	st.RetriesWaiting = retries
+
+	return &st, nil
+}
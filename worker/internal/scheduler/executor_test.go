@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestExecutorPriority(t *testing.T) {
+	e := newExecutor(1)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	var order []string
+	record := func(name string) func(context.Context) {
+		return func(context.Context) { order = append(order, name) }
+	}
+
+	first := e.submit(ctx, "a", model.JobTriggerScheduled, time.Now(), func(context.Context) { <-release })
+	<-first.started
+
+	scheduled := e.submit(ctx, "b", model.JobTriggerScheduled, time.Now(), record("scheduled"))
+	retry := e.submit(ctx, "c", model.JobTriggerRetry, time.Now(), record("retry"))
+	manual := e.submit(ctx, "d", model.JobTriggerManual, time.Now(), record("manual"))
+
+	if st := e.stats(time.Now()); st.Running != 1 || st.Pending != 3 {
+		t.Fatalf("expected 1 running and 3 pending, got %d and %d", st.Running, st.Pending)
+	}
+
+	close(release)
+	for _, task := range []*execTask{scheduled, retry, manual} {
+		<-task.done
+	}
+
+	want := []string{"manual", "retry", "scheduled"}
+	if len(order) != len(want) {
+		t.Fatalf("expected %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, order)
+		}
+	}
+}
+
+func TestExecutorOneRunPerSite(t *testing.T) {
+	e := newExecutor(3)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	block := func(context.Context) { <-release }
+
+	first := e.submit(ctx, "a", model.JobTriggerManual, time.Now(), block)
+	second := e.submit(ctx, "a", model.JobTriggerManual, time.Now(), block)
+	other := e.submit(ctx, "b", model.JobTriggerScheduled, time.Now(), block)
+
+	<-first.started
+	<-other.started
+	select {
+	case <-second.started:
+		t.Fatal("second run of site a started while the first was running")
+	default:
+	}
+	if e.submitIfIdle(ctx, "a", model.JobTriggerManual, time.Now(), block) != nil {
+		t.Fatal("expected submitIfIdle to refuse a run of a running site")
+	}
+
+	close(release)
+	<-second.done
+
+	e.stop()
+	dropped := e.submit(ctx, "a", model.JobTriggerManual, time.Now(), block)
+	select {
+	case <-dropped.done:
+	default:
+		t.Fatal("expected a stopped executor to drop new runs")
+	}
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
+)
+
+// fakeService records the jobs a scheduler creates and runs; run decides
+// the outcome of RunJob
+type fakeService struct {
+	mu     sync.Mutex
+	nextID int
+	queued []*model.JobStatus
+	ran    []*model.JobStatus
+	run    func(ctx context.Context, job *model.JobStatus) error
+}
+
+func (f *fakeService) NewJob(siteName, url, trigger string) *model.JobStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	return &model.JobStatus{
+		JobID:    fmt.Sprintf("job-%d", f.nextID),
+		SiteName: siteName,
+		URL:      url,
+		Trigger:  trigger,
+		Attempt:  1,
+	}
+}
+
+func (f *fakeService) QueueJob(_ context.Context, job *model.JobStatus, queuedAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.Status = model.JobStatusQueued
+	job.QueuedAt = queuedAt
+	f.queued = append(f.queued, job)
+	return nil
+}
+
+func (f *fakeService) RunJob(ctx context.Context, job *model.JobStatus) error {
+	f.mu.Lock()
+	f.ran = append(f.ran, job)
+	run := f.run
+	f.mu.Unlock()
+
+	if run != nil {
+		if err := run(ctx, job); err != nil {
+			job.Status = model.JobStatusFailed
+			return err
+		}
+	}
+	job.Status = model.JobStatusCompleted
+	return nil
+}
+
+func (f *fakeService) AbortJob(_ context.Context, job *model.JobStatus, _ error) {
+	job.Status = model.JobStatusInterrupted
+}
+
+func (f *fakeService) ListJobs(context.Context, *storage.RunFilter) ([]*model.JobStatus, error) {
+	return nil, nil
+}
+
+func (f *fakeService) ranJobs() []*model.JobStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*model.JobStatus(nil), f.ran...)
+}
+
+// newTestScheduler returns a scheduler backed by an in-memory Redis, with
+// the given sites configured
+func newTestScheduler(t *testing.T, mr *miniredis.Miniredis, workerID string, cfg config.SchedulerConfig, sites ...string) (*Scheduler, *fakeService) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	svc := &fakeService{}
+	s := New(svc, client, workerID, cfg, zap.NewNop())
+	for _, site := range sites {
+		s.SetSiteOptions(site, SiteOptions{})
+	}
+	return s, svc
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
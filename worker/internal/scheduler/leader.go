@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// leaderKey holds the worker ID of the leader while leader election is on.
+// The leader renews it every leaderRenewInterval; if it stops, another worker
+// takes over once leaderTTL has passed.
+const leaderKey = "scheduler:leader"
+
+const (
+	leaderTTL           = 15 * time.Second
+	leaderRenewInterval = leaderTTL / 3
+)
+
+// elect keeps trying to become the leader, and renews the leadership while
+// it holds it, until ctx is cancelled. A worker that becomes the leader
+// catches up missed runs; one that stops releases the leadership so another
+// worker takes over right away.
+func (s *Scheduler) elect(ctx context.Context) {
+	ticker := time.NewTicker(leaderRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.campaign(ctx) {
+			s.catchUpMissed(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			if s.leader.Load() {
+				s.resign(context.WithoutCancel(ctx))
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign takes or renews the leadership. It reports true if this worker
+// has just become the leader. A leader that cannot renew steps down before
+// its key can expire, so that it never drives schedules alongside the worker
+// taking over.
+func (s *Scheduler) campaign(ctx context.Context) bool {
+	// The lease counts from before the request, which may be slow
+	attempt := time.Now()
+
+	if s.leader.Load() {
+		n, err := renewScript.Run(ctx, s.redis, []string{leaderKey}, s.workerID, leaderTTL.Milliseconds()).Int64()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("failed to renew leadership", zap.Error(err))
+			}
+			if time.Since(s.leaderRenewed) >= leaderTTL-leaderRenewInterval {
+				s.leader.Store(false)
+				s.logger.Warn("stepped down, leadership could not be renewed",
+					zap.String("worker", s.workerID),
+					zap.Time("last_renewed", s.leaderRenewed))
+			}
+			return false
+		}
+		if n == 0 {
+			s.leader.Store(false)
+			s.logger.Warn("leadership lost", zap.String("worker", s.workerID))
+			return false
+		}
+		s.leaderRenewed = attempt
+		return false
+	}
+
+	ok, err := s.redis.SetNX(ctx, leaderKey, s.workerID, leaderTTL).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("failed to campaign for leadership", zap.Error(err))
+		}
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	s.leaderRenewed = attempt
+	s.leader.Store(true)
+	s.logger.Info("became leader", zap.String("worker", s.workerID))
+	return true
+}
+
+func (s *Scheduler) resign(ctx context.Context) {
+	s.leader.Store(false)
+	if err := releaseScript.Run(ctx, s.redis, []string{leaderKey}, s.workerID).Err(); err != nil {
+		s.logger.Warn("failed to release leadership", zap.Error(err))
+		return
+	}
+	s.logger.Info("leadership released", zap.String("worker", s.workerID))
+}
+
+// Leader returns the worker ID of the current leader. It is empty if leader
+// election is off or no worker holds the leadership.
+func (s *Scheduler) Leader(ctx context.Context) (string, error) {
+	if !s.election {
+		return "", nil
+	}
+
+	id, err := s.redis.Get(ctx, leaderKey).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("loading leader: %w", err)
+	}
+	return id, nil
+}
+
+// drivesSchedules reports whether this worker fires the cron schedules: every
+// worker does without leader election, only the leader with it
+func (s *Scheduler) drivesSchedules() bool {
+	return !s.election || s.leader.Load()
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestCampaignFailover(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	cfg := config.SchedulerConfig{LeaderElection: true}
+	s1, _ := newTestScheduler(t, mr, "worker-1", cfg)
+	s2, _ := newTestScheduler(t, mr, "worker-2", cfg)
+
+	if !s1.campaign(ctx) {
+		t.Fatal("expected worker-1 to become leader")
+	}
+	if s2.campaign(ctx) || s2.drivesSchedules() {
+		t.Fatal("expected worker-2 to stay a follower")
+	}
+	if leader, _ := s2.Leader(ctx); leader != "worker-1" {
+		t.Fatalf("expected worker-1 as leader, got %q", leader)
+	}
+
+	// Renewing keeps the leadership past the original lease
+	mr.FastForward(leaderRenewInterval)
+	s1.campaign(ctx)
+	mr.FastForward(leaderTTL - leaderRenewInterval)
+	if s2.campaign(ctx) {
+		t.Fatal("expected the renewed leadership to hold")
+	}
+
+	// worker-1 stops renewing; worker-2 takes over once the lease expires
+	mr.FastForward(leaderTTL)
+	if !s2.campaign(ctx) {
+		t.Fatal("expected worker-2 to take over")
+	}
+	s1.campaign(ctx)
+	if s1.drivesSchedules() {
+		t.Fatal("expected worker-1 to notice the lost leadership")
+	}
+	if !s2.drivesSchedules() {
+		t.Fatal("expected worker-2 to drive schedules")
+	}
+}
+
+func TestCampaignStepsDownWithoutRenewal(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{LeaderElection: true})
+
+	if !s.campaign(ctx) {
+		t.Fatal("expected worker-1 to become leader")
+	}
+
+	mr.SetError("connection refused")
+	defer mr.SetError("")
+
+	// A single failed renewal is tolerated
+	s.campaign(ctx)
+	if !s.drivesSchedules() {
+		t.Fatal("expected the leader to survive one failed renewal")
+	}
+
+	// One without a renewal for most of the lease steps down
+	s.leaderRenewed = time.Now().Add(-(leaderTTL - leaderRenewInterval))
+	s.campaign(ctx)
+	if s.drivesSchedules() {
+		t.Fatal("expected the leader to step down")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, svc := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{LeaderElection: true}, "site-a")
+
+	first := svc.NewJob("site-a", "https://example.com/a", model.JobTriggerScheduled)
+	s.dispatch(ctx, first)
+	if first.Status != model.JobStatusQueued {
+		t.Fatalf("expected the job to be recorded as queued, got %q", first.Status)
+	}
+
+	// A site already waiting in the queue skips the next run
+	s.dispatch(ctx, svc.NewJob("site-a", "https://example.com/a", model.JobTriggerScheduled))
+
+	queue, _ := mr.List(queueKey)
+	if len(queue) != 1 {
+		t.Fatalf("expected one queue entry, got %v", queue)
+	}
+	if slot, _ := mr.Get(queuedKeyPrefix + "site-a"); slot != first.JobID {
+		t.Fatalf("expected the queued slot to hold %s, got %q", first.JobID, slot)
+	}
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/service"
+)
+
+// lockTTL is the lease of a site lock; a running job renews it every
+// lockRenewInterval, so a crashed worker blocks its site for at most lockTTL
+const (
+	lockTTL           = 2 * time.Minute
+	lockRenewInterval = lockTTL / 3
+)
+
+// ErrLeaseLost cancels a job whose site lock expired or was taken over
+var ErrLeaseLost = errors.New("lock lease lost")
+
+// lease is a held site lock. Every acquisition gets a fencing token from a
+// per-site counter, so a later holder always has a larger token than an
+// earlier one and writes of an earlier holder can be told apart.
+type lease struct {
+	key   string
+	value string
+	token int64
+}
+
+// acquireScript takes the lock and draws the next fencing token in one step,
+// so a token is only used by a holder that actually got the lock
+var acquireScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call("INCR", KEYS[2])
+redis.call("SET", KEYS[1], ARGV[1] .. ":" .. token, "PX", ARGV[2])
+return token
+`)
+
+// renewScript extends the lease if it is still held with the same value
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock if it is still held with the same value
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func lockKey(siteName string) string {
+	return fmt.Sprintf("job:lock:%s", siteName)
+}
+
+func fenceKey(siteName string) string {
+	return fmt.Sprintf("job:fence:%s", siteName)
+}
+
+// acquireLock acquires the lock of a site; it returns nil if another worker
+// holds it
+func (s *Scheduler) acquireLock(ctx context.Context, siteName string) (*lease, error) {
+	key := lockKey(siteName)
+
+	token, err := acquireScript.Run(ctx, s.redis,
+		[]string{key, fenceKey(siteName)},
+		s.workerID, lockTTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("acquiring lock: %w", err)
+	}
+	if token == 0 {
+		return nil, nil
+	}
+
+	return &lease{
+		key:   key,
+		value: s.workerID + ":" + strconv.FormatInt(token, 10),
+		token: token,
+	}, nil
+}
+
+// renewLock extends the lease; it reports false if the lock is no longer held
+func (s *Scheduler) renewLock(ctx context.Context, l *lease) (bool, error) {
+	n, err := renewScript.Run(ctx, s.redis, []string{l.key}, l.value, lockTTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("renewing lock: %w", err)
+	}
+	return n == 1, nil
+}
+
+// releaseLock releases the lock if this lease still holds it
+func (s *Scheduler) releaseLock(ctx context.Context, l *lease) error {
+	if err := releaseScript.Run(ctx, s.redis, []string{l.key}, l.value).Err(); err != nil {
+		return fmt.Errorf("releasing lock: %w", err)
+	}
+	return nil
+}
+
+// heartbeat renews the lease until ctx is done. If the lock was taken over,
+// or could not be renewed before the lease ran out, the job is cancelled
+// with ErrLeaseLost. It also picks up cancel requests for the job that were
+// missed on pub/sub and stops the job when the site's quiet hours begin.
+func (s *Scheduler) heartbeat(ctx context.Context, l *lease, siteName, jobID string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(lockRenewInterval)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if s.cancelRequested(ctx, jobID) {
+			cancel(service.ErrJobCancelled)
+			return
+		}
+		if s.siteOptions(siteName).quietAt(time.Now()) {
+			s.logger.Info("quiet hours started, cancelling job", zap.String("job_id", jobID))
+			cancel(errQuietHoursCancel)
+			return
+		}
+
+		held, err := s.renewLock(ctx, l)
+		switch {
+		case err == nil && held:
+			renewed = time.Now()
+			continue
+		case err == nil:
+			s.logger.Error("lock taken over, cancelling job",
+				zap.String("lock", l.key),
+				zap.Int64("fence_token", l.token))
+		case time.Since(renewed) < lockTTL-lockRenewInterval:
+			s.logger.Warn("failed to renew lock, retrying",
+				zap.String("lock", l.key),
+				zap.Error(err))
+			continue
+		default:
+			s.logger.Error("lock lease expired, cancelling job",
+				zap.String("lock", l.key),
+				zap.Int64("fence_token", l.token),
+				zap.Error(err))
+		}
+
+		cancel(ErrLeaseLost)
+		return
+	}
+}
@@ -0,0 +1,360 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/service"
+)
+
+// Redis keys of the manual trigger queue. New jobs are pushed on the left of
+// queueKey and consumed from the right into a per-worker processing list, so
+// a job taken by a worker that dies is recovered when that worker restarts or,
+// once its heartbeat expires, by any other worker.
+const (
+	queueKey            = "scrape:queue"
+	processingKeyPrefix = "scrape:processing:"
+	queuedKeyPrefix     = "scrape:queued:"
+)
+
+// queuedKeyTTL bounds how long a lost queue entry can block new triggers for its site
+const queuedKeyTTL = 24 * time.Hour
+
+// queuePollTimeout is how long a consumer blocks waiting for a job before it
+// checks whether it should stop
+const queuePollTimeout = 2 * time.Second
+
+// queueRetryDelay is how long a consumer waits after putting back a job whose
+// site is locked by another run
+const queueRetryDelay = 5 * time.Second
+
+// reclaimInterval is how often a consumer looks for processing lists left
+// by dead workers
+const reclaimInterval = workerTTL
+
+// ErrAlreadyQueued is returned when a site already has a manual scrape waiting
+var ErrAlreadyQueued = errors.New("scrape already queued")
+
+// queuedJob is the queue entry of a manually triggered scrape, a retry or,
+// with leader election, a scheduled or caught-up run
+type queuedJob struct {
+	JobID    string    `json:"job_id"`
+	SiteName string    `json:"site_name"`
+	URL      string    `json:"url"`
+	QueuedAt time.Time `json:"queued_at"`
+	Trigger  string    `json:"trigger,omitempty"` // empty for manual triggers
+	Attempt  int       `json:"attempt,omitempty"`
+	RetryOf  string    `json:"retry_of,omitempty"`
+}
+
+// Enqueue queues a manual scrape of siteName starting at url for any worker
+// to pick up and records it as a queued run. Only one scrape per site can be
+// waiting at a time; further triggers fail with ErrAlreadyQueued until it
+// starts. Sites in their quiet hours refuse new triggers with ErrQuietHours.
+func (s *Scheduler) Enqueue(ctx context.Context, siteName, url string) (*model.JobStatus, error) {
+	if s.siteOptions(siteName).quietAt(time.Now()) {
+		return nil, ErrQuietHours
+	}
+
+	job := s.service.NewJob(siteName, url, model.JobTriggerManual)
+	if err := s.enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// enqueue reserves the queued slot of the job's site, records the job as
+// queued and pushes it onto the shared queue
+func (s *Scheduler) enqueue(ctx context.Context, job *model.JobStatus) error {
+	queuedKey := queuedKeyPrefix + job.SiteName
+
+	ok, err := s.redis.SetNX(ctx, queuedKey, job.JobID, queuedKeyTTL).Result()
+	if err != nil {
+		return fmt.Errorf("reserving queue slot: %w", err)
+	}
+	if !ok {
+		existing, _ := s.redis.Get(ctx, queuedKey).Result()
+		return fmt.Errorf("%w for %s as job %s", ErrAlreadyQueued, job.SiteName, existing)
+	}
+
+	entry := queuedJob{
+		JobID:    job.JobID,
+		SiteName: job.SiteName,
+		URL:      job.URL,
+		QueuedAt: time.Now(),
+		Trigger:  job.Trigger,
+		Attempt:  job.Attempt,
+		RetryOf:  job.RetryOf,
+	}
+
+	// Record the run before pushing it, so a consumer never overwrites a
+	// running record with the queued one
+	if err := s.service.QueueJob(ctx, job, entry.QueuedAt); err != nil {
+		s.redis.Del(ctx, queuedKey)
+		return fmt.Errorf("recording queued job: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		s.redis.Del(ctx, queuedKey)
+		return fmt.Errorf("marshaling queued job: %w", err)
+	}
+
+	if err := s.redis.LPush(ctx, queueKey, data).Err(); err != nil {
+		s.redis.Del(ctx, queuedKey)
+		return fmt.Errorf("queueing job: %w", err)
+	}
+
+	s.logger.Info("scrape queued",
+		zap.String("site", job.SiteName),
+		zap.String("job_id", job.JobID),
+		zap.String("trigger", job.Trigger),
+		zap.String("url", job.URL))
+
+	return nil
+}
+
+// consumeQueue hands queued scrapes to the executor until ctx is cancelled.
+// It takes the next entry only once the previous one has a slot, so entries
+// this worker cannot start yet stay available to the other workers.
+func (s *Scheduler) consumeQueue(ctx context.Context) {
+	processingKey := processingKeyPrefix + s.workerID
+	s.recoverProcessing(ctx, processingKey)
+
+	var lastReclaim time.Time
+	skipped := make(map[string]bool) // entries put back since the last start
+	for ctx.Err() == nil {
+		if time.Since(lastReclaim) >= reclaimInterval {
+			s.reclaimProcessing(ctx)
+			lastReclaim = time.Now()
+		}
+
+		select {
+		case <-s.requeued:
+			sleep(ctx, queueRetryDelay)
+			continue
+		default:
+		}
+
+		data, err := s.redis.BLMove(ctx, queueKey, processingKey, "RIGHT", "LEFT", queuePollTimeout).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("failed to read scrape queue", zap.Error(err))
+				sleep(ctx, queueRetryDelay)
+			}
+			continue
+		}
+
+		var entry queuedJob
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			s.logger.Error("dropping malformed queue entry", zap.String("entry", data), zap.Error(err))
+			s.redis.LRem(context.WithoutCancel(ctx), processingKey, 1, data)
+			continue
+		}
+
+		trigger := entry.Trigger
+		if trigger == "" {
+			trigger = model.JobTriggerManual
+		}
+		task := s.exec.submitIfIdle(ctx, entry.SiteName, trigger, entry.QueuedAt, func(ctx context.Context) {
+			if !s.runQueued(ctx, processingKey, data, entry) {
+				s.signalRequeued()
+			}
+		})
+		if task == nil {
+			// The site is running on this worker and waiting for it would
+			// hold up every entry behind this one. Once an entry comes round
+			// again, every entry was tried and the consumer pauses.
+			s.putBack(ctx, processingKey, data, entry)
+			if skipped[entry.JobID] {
+				clear(skipped)
+				s.signalRequeued()
+			}
+			skipped[entry.JobID] = true
+			continue
+		}
+		clear(skipped)
+
+		select {
+		case <-task.started:
+		case <-task.done:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// runQueued runs one queue entry under the site lock. It reports false if the
+// site was locked and the entry went back to the queue. An entry dropped by
+// the executor on shutdown stays in the processing list for recoverProcessing.
+func (s *Scheduler) runQueued(ctx context.Context, processingKey, data string, entry queuedJob) bool {
+	// The entry stays in the processing list until it is done with, so a
+	// crash in between leaves it for recoverProcessing
+	defer s.redis.LRem(context.WithoutCancel(ctx), processingKey, 1, data)
+
+	job := &model.JobStatus{
+		JobID:    entry.JobID,
+		SiteName: entry.SiteName,
+		URL:      entry.URL,
+		Trigger:  entry.Trigger,
+		Attempt:  max(entry.Attempt, 1),
+		RetryOf:  entry.RetryOf,
+		QueuedAt: entry.QueuedAt,
+	}
+	if job.Trigger == "" {
+		job.Trigger = model.JobTriggerManual
+	}
+
+	// The queued slot is released once the job starts so the site can be
+	// triggered again while it runs
+	onStart := func() {
+		releaseScript.Run(context.WithoutCancel(ctx), s.redis,
+			[]string{queuedKeyPrefix + entry.SiteName}, entry.JobID)
+	}
+
+	if s.cancelRequested(ctx, job.JobID) {
+		onStart()
+		s.service.AbortJob(ctx, job, service.ErrJobCancelled)
+		s.logger.Info("queued scrape cancelled",
+			zap.String("site", entry.SiteName),
+			zap.String("job_id", entry.JobID))
+		return true
+	}
+
+	if s.siteOptions(entry.SiteName).quietAt(time.Now()) {
+		onStart()
+		s.service.AbortJob(ctx, job, errQuietHoursCancel)
+		s.logger.Info("queued scrape dropped in quiet hours",
+			zap.String("site", entry.SiteName),
+			zap.String("job_id", entry.JobID))
+		return true
+	}
+
+	ran, err := s.runLocked(ctx, job, onStart)
+	if err != nil {
+		s.logger.Error("failed to acquire lock",
+			zap.String("site", entry.SiteName),
+			zap.Error(err))
+	}
+	if ran {
+		return true
+	}
+
+	if err := s.redis.LPush(context.WithoutCancel(ctx), queueKey, data).Err(); err != nil {
+		s.logger.Error("failed to requeue scrape",
+			zap.String("site", entry.SiteName),
+			zap.String("job_id", entry.JobID),
+			zap.Error(err))
+	}
+	return false
+}
+
+// putBack returns an entry this worker cannot start to the far end of the
+// queue
+func (s *Scheduler) putBack(ctx context.Context, processingKey, data string, entry queuedJob) {
+	ctx = context.WithoutCancel(ctx)
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, queueKey, data)
+		pipe.LRem(ctx, processingKey, 1, data)
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("failed to requeue scrape",
+			zap.String("site", entry.SiteName),
+			zap.String("job_id", entry.JobID),
+			zap.Error(err))
+	}
+}
+
+// signalRequeued makes the consumer pause before taking the next entry
+func (s *Scheduler) signalRequeued() {
+	select {
+	case s.requeued <- struct{}{}:
+	default:
+	}
+}
+
+// recoverProcessing puts back entries this worker took but never finished
+func (s *Scheduler) recoverProcessing(ctx context.Context, processingKey string) {
+	for {
+		data, err := s.redis.LMove(ctx, processingKey, queueKey, "RIGHT", "RIGHT").Result()
+		if err == redis.Nil {
+			return
+		}
+		if err != nil {
+			s.logger.Error("failed to recover queued scrapes", zap.Error(err))
+			return
+		}
+		s.logger.Info("recovered unfinished queued scrape", zap.String("entry", data))
+	}
+}
+
+// reclaimScript moves the entries of a processing list back to the queue,
+// unless its worker has a heartbeat. KEYS[1] is the worker's heartbeat key,
+// KEYS[2] its processing list and KEYS[3] the queue.
+var reclaimScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return {}
+end
+local moved = {}
+while true do
+	local entry = redis.call("LMOVE", KEYS[2], KEYS[3], "RIGHT", "RIGHT")
+	if not entry then
+		break
+	end
+	table.insert(moved, entry)
+end
+return moved
+`)
+
+// reclaimProcessing puts back the entries of the processing lists of workers
+// whose heartbeat expired, which may never restart under the same ID
+func (s *Scheduler) reclaimProcessing(ctx context.Context) {
+	iter := s.redis.Scan(ctx, 0, processingKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		workerID := strings.TrimPrefix(key, processingKeyPrefix)
+		if workerID == s.workerID {
+			continue
+		}
+
+		moved, err := reclaimScript.Run(ctx, s.redis,
+			[]string{workerKeyPrefix + workerID, key, queueKey}).StringSlice()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("failed to reclaim queued scrapes",
+					zap.String("worker", workerID),
+					zap.Error(err))
+			}
+			continue
+		}
+		for _, data := range moved {
+			s.logger.Info("reclaimed queued scrape of dead worker",
+				zap.String("worker", workerID),
+				zap.String("entry", data))
+		}
+	}
+	if err := iter.Err(); err != nil && ctx.Err() == nil {
+		s.logger.Error("failed to scan processing lists", zap.Error(err))
+	}
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
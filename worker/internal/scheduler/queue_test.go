@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestReclaimProcessing(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{}, "site-a", "site-b")
+
+	entry := func(site, jobID string) string {
+		data, _ := json.Marshal(queuedJob{JobID: jobID, SiteName: site, QueuedAt: time.Now()})
+		return string(data)
+	}
+	dead := entry("site-a", "job-dead")
+	live := entry("site-b", "job-live")
+
+	// worker-2 died with a queued scrape; worker-3 is alive and running one
+	mr.Lpush(processingKeyPrefix+"worker-2", dead)
+	mr.Set(queuedKeyPrefix+"site-a", "job-dead")
+	mr.Lpush(processingKeyPrefix+"worker-3", live)
+	mr.Set(queuedKeyPrefix+"site-b", "job-live")
+	mr.Set(workerKeyPrefix+"worker-3", "{}")
+
+	s.reclaimProcessing(ctx)
+
+	queue, _ := mr.List(queueKey)
+	if len(queue) != 1 || queue[0] != dead {
+		t.Fatalf("expected the dead worker's entry in the queue, got %v", queue)
+	}
+	if mr.Exists(processingKeyPrefix + "worker-2") {
+		t.Error("expected the dead worker's processing list to be emptied")
+	}
+	// The reclaimed entry still waits, so the site keeps rejecting triggers
+	if _, err := s.Enqueue(ctx, "site-a", "https://example.com/a"); !errors.Is(err, ErrAlreadyQueued) {
+		t.Errorf("expected ErrAlreadyQueued for the reclaimed site, got %v", err)
+	}
+
+	if got, _ := mr.List(processingKeyPrefix + "worker-3"); len(got) != 1 {
+		t.Errorf("expected the live worker's entry to stay, got %v", got)
+	}
+	if !mr.Exists(queuedKeyPrefix + "site-b") {
+		t.Error("expected the live worker's queued slot to stay")
+	}
+}
+
+func TestConsumeQueueSkipsRunningSite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	s, svc := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{Workers: 2}, "site-a", "site-b")
+
+	// site-a is busy with a run on this worker
+	release := make(chan struct{})
+	defer close(release)
+	busy := s.exec.submit(ctx, "site-a", model.JobTriggerScheduled, time.Now(), func(context.Context) { <-release })
+	<-busy.started
+
+	queuedA, err := s.Enqueue(ctx, "site-a", "https://example.com/a")
+	if err != nil {
+		t.Fatalf("enqueueing site-a: %v", err)
+	}
+	if _, err := s.Enqueue(ctx, "site-b", "https://example.com/b"); err != nil {
+		t.Fatalf("enqueueing site-b: %v", err)
+	}
+
+	go s.consumeQueue(ctx)
+
+	waitFor(t, "the site-b run", func() bool {
+		for _, job := range svc.ranJobs() {
+			if job.SiteName == "site-b" {
+				return true
+			}
+		}
+		return false
+	})
+
+	queue, _ := mr.List(queueKey)
+	if len(queue) != 1 {
+		t.Fatalf("expected the site-a entry back in the queue, got %v", queue)
+	}
+	var entry queuedJob
+	if err := json.Unmarshal([]byte(queue[0]), &entry); err != nil || entry.JobID != queuedA.JobID {
+		t.Fatalf("expected job %s in the queue, got %v", queuedA.JobID, queue)
+	}
+}
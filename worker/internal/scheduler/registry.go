@@ -0,0 +1,239 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Redis keys of the worker registry. Every live worker keeps its heartbeat in
+// its own key, which expires when the worker stops renewing it; the set lists
+// the IDs of all workers seen, and IDs whose heartbeat expired are removed
+// when the registry is read.
+const (
+	workersKey      = "scheduler:workers"
+	workerKeyPrefix = "scheduler:worker:"
+)
+
+// workerHeartbeatInterval is how often a worker publishes its heartbeat; a
+// worker is considered dead once workerTTL passes without one
+const (
+	workerHeartbeatInterval = 10 * time.Second
+	workerTTL               = 3 * workerHeartbeatInterval
+)
+
+// WorkerInfo is the heartbeat of a worker
+type WorkerInfo struct {
+	WorkerID  string       `json:"worker_id"`
+	Version   string       `json:"version"`
+	Leader    bool         `json:"leader"`
+	StartedAt time.Time    `json:"started_at"`
+	LastSeen  time.Time    `json:"last_seen"`
+	PoolSize  int          `json:"pool_size"`
+	Sites     []string     `json:"sites"`
+	Jobs      []RunningJob `json:"jobs"`
+}
+
+// RunningJob is a job running on a worker
+type RunningJob struct {
+	JobID      string    `json:"job_id"`
+	SiteName   string    `json:"site_name"`
+	Trigger    string    `json:"trigger"`
+	FenceToken int64     `json:"fence_token"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// SiteLock is a held site lock. Stale is set when the holder is not a live
+// worker; such a lock blocks its site until the lease expires.
+type SiteLock struct {
+	SiteName   string  `json:"site_name"`
+	WorkerID   string  `json:"worker_id"`
+	FenceToken int64   `json:"fence_token"`
+	TTL        float64 `json:"ttl_seconds"`
+	Stale      bool    `json:"stale"`
+}
+
+// publishWorker publishes the heartbeat of this worker until ctx is
+// cancelled, then removes it from the registry
+func (s *Scheduler) publishWorker(ctx context.Context) {
+	ticker := time.NewTicker(workerHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.registerWorker(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("failed to publish worker heartbeat", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.deregisterWorker(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// workerInfo returns the current heartbeat of this worker
+func (s *Scheduler) workerInfo() WorkerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := WorkerInfo{
+		WorkerID:  s.workerID,
+		Version:   s.version,
+		Leader:    s.election && s.leader.Load(),
+		StartedAt: s.startedAt,
+		LastSeen:  time.Now(),
+		PoolSize:  s.exec.size,
+		Sites:     make([]string, 0, len(s.sites)),
+		Jobs:      make([]RunningJob, 0, len(s.running)),
+	}
+	for name := range s.sites {
+		info.Sites = append(info.Sites, name)
+	}
+	for _, rj := range s.running {
+		info.Jobs = append(info.Jobs, rj.info)
+	}
+	slices.Sort(info.Sites)
+	slices.SortFunc(info.Jobs, func(a, b RunningJob) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return info
+}
+
+func (s *Scheduler) registerWorker(ctx context.Context) error {
+	data, err := json.Marshal(s.workerInfo())
+	if err != nil {
+		return fmt.Errorf("marshaling worker info: %w", err)
+	}
+
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, workerKeyPrefix+s.workerID, data, workerTTL)
+		pipe.SAdd(ctx, workersKey, s.workerID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("publishing worker heartbeat: %w", err)
+	}
+	return nil
+}
+
+func (s *Scheduler) deregisterWorker(ctx context.Context) {
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, workerKeyPrefix+s.workerID)
+		pipe.SRem(ctx, workersKey, s.workerID)
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("failed to remove worker from registry", zap.Error(err))
+	}
+}
+
+// ListWorkers returns the live workers ordered by ID. Workers whose heartbeat
+// expired are removed from the registry.
+func (s *Scheduler) ListWorkers(ctx context.Context) ([]WorkerInfo, error) {
+	ids, err := s.redis.SMembers(ctx, workersKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("loading workers: %w", err)
+	}
+	if len(ids) == 0 {
+		return []WorkerInfo{}, nil
+	}
+	slices.Sort(ids)
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = workerKeyPrefix + id
+	}
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("loading worker heartbeats: %w", err)
+	}
+
+	workers := make([]WorkerInfo, 0, len(ids))
+	var dead []any
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			dead = append(dead, ids[i])
+			continue
+		}
+
+		var info WorkerInfo
+		if err := json.Unmarshal([]byte(data), &info); err != nil {
+			s.logger.Warn("skipping malformed worker heartbeat", zap.String("worker", ids[i]), zap.Error(err))
+			continue
+		}
+		workers = append(workers, info)
+	}
+
+	if len(dead) > 0 {
+		if err := s.redis.SRem(ctx, workersKey, dead...).Err(); err != nil {
+			s.logger.Warn("failed to remove dead workers", zap.Error(err))
+		}
+	}
+	return workers, nil
+}
+
+// ListLocks returns the held site locks ordered by site, flagging those held
+// by a worker that is not in live
+func (s *Scheduler) ListLocks(ctx context.Context, live []WorkerInfo) ([]SiteLock, error) {
+	alive := make(map[string]bool, len(live))
+	for _, w := range live {
+		alive[w.WorkerID] = true
+	}
+
+	prefix := lockKey("")
+	var locks []SiteLock
+
+	iter := s.redis.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		value, err := s.redis.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue // released since the scan
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading lock %s: %w", key, err)
+		}
+		ttl, err := s.redis.PTTL(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("loading lock %s: %w", key, err)
+		}
+
+		lock := SiteLock{SiteName: strings.TrimPrefix(key, prefix), TTL: ttl.Seconds()}
+		lock.WorkerID, lock.FenceToken = parseLockValue(value)
+		lock.Stale = !alive[lock.WorkerID]
+		locks = append(locks, lock)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("scanning locks: %w", err)
+	}
+
+	slices.SortFunc(locks, func(a, b SiteLock) int {
+		return strings.Compare(a.SiteName, b.SiteName)
+	})
+	return locks, nil
+}
+
+// parseLockValue splits a lock value written by acquireScript into the
+// holder's worker ID and the fencing token
+func parseLockValue(value string) (string, int64) {
+	i := strings.LastIndexByte(value, ':')
+	if i < 0 {
+		return value, 0
+	}
+	token, err := strconv.ParseInt(value[i+1:], 10, 64)
+	if err != nil {
+		return value, 0
+	}
+	return value[:i], token
+}
@@ -0,0 +1,23 @@
+package scheduler
+
+import "testing"
+
+func TestParseLockValue(t *testing.T) {
+	tests := []struct {
+		value  string
+		worker string
+		token  int64
+	}{
+		{value: "worker-1:42", worker: "worker-1", token: 42},
+		{value: "host:with:colons:7", worker: "host:with:colons", token: 7},
+		{value: "legacy-worker", worker: "legacy-worker"},
+		{value: "worker-1:abc", worker: "worker-1:abc"},
+	}
+
+	for _, tt := range tests {
+		worker, token := parseLockValue(tt.value)
+		if worker != tt.worker || token != tt.token {
+			t.Errorf("%q: expected %q/%d, got %q/%d", tt.value, tt.worker, tt.token, worker, token)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// Redis keys of job retries. Retries wait in a sorted set scored by their
+// due time and are moved to the queue when due; runs that failed every
+// attempt end up in the dead-letter list, newest first.
+const (
+	retriesKey    = "scrape:retries"
+	deadLetterKey = "scrape:dead_letter"
+)
+
+const (
+	// defaultRetryBackoff is the first retry delay of a policy without one
+	defaultRetryBackoff = time.Minute
+
+	// retryPollInterval is how often due retries are moved to the queue
+	retryPollInterval = 5 * time.Second
+
+	// retryPromoteBatch bounds the retries moved to the queue per poll
+	retryPromoteBatch = 100
+
+	// maxDeadLetters bounds the dead-letter list; older entries are dropped
+	maxDeadLetters = 1000
+)
+
+// ErrDeadLetterNotFound is returned for a job ID that is not in the dead-letter list
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// RetryPolicy decides when a failed scheduled run is tried again
+type RetryPolicy struct {
+	// MaxAttempts is the number of retries after the first failure
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, doubled for each further
+	// one up to MaxBackoff; zero MaxBackoff means unbounded
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// delay returns how long to wait before retrying a run that failed attempt
+// times. It reports false once the retries are used up.
+func (p RetryPolicy) delay(attempt int) (time.Duration, bool) {
+	if attempt > p.MaxAttempts {
+		return 0, false
+	}
+
+	d := p.Backoff
+	if d <= 0 {
+		d = defaultRetryBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d, true
+}
+
+// DeadLetter is a scheduled run that failed on every attempt
+type DeadLetter struct {
+	JobID    string    `json:"job_id"`
+	SiteName string    `json:"site_name"`
+	URL      string    `json:"url,omitempty"`
+	Trigger  string    `json:"trigger"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// promoteScript moves due retries to the queue atomically, so each retry is
+// queued exactly once however many workers poll. Like Enqueue, a retry takes
+// its site's queued slot first; a retry whose site already has a run waiting
+// stays in the set until the slot is free. ARGV[3] is the queued key prefix
+// and ARGV[4] the slot TTL in seconds.
+var promoteScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+local promoted = 0
+for _, entry in ipairs(due) do
+	local job = cjson.decode(entry)
+	if redis.call("SET", ARGV[3] .. job.site_name, job.job_id, "NX", "EX", ARGV[4]) then
+		redis.call("ZREM", KEYS[1], entry)
+		redis.call("LPUSH", KEYS[2], entry)
+		promoted = promoted + 1
+	end
+end
+return promoted
+`)
+
+// retryOrDeadLetter schedules the next attempt of a failed run, or moves it
+// to the dead-letter list once its site's retries are used up
+func (s *Scheduler) retryOrDeadLetter(ctx context.Context, job *model.JobStatus, runErr error) {
+	attempt := max(job.Attempt, 1)
+
+	delay, ok := s.siteOptions(job.SiteName).Retry.delay(attempt)
+	if !ok {
+		s.deadLetter(ctx, job, attempt, runErr)
+		return
+	}
+
+	retry := s.service.NewJob(job.SiteName, job.URL, model.JobTriggerRetry)
+	retry.Attempt = attempt + 1
+	retry.RetryOf = job.JobID
+	if job.RetryOf != "" {
+		retry.RetryOf = job.RetryOf
+	}
+
+	due := time.Now().Add(delay)
+	if err := s.service.QueueJob(ctx, retry, due); err != nil {
+		s.logger.Error("failed to record retry", zap.String("job_id", job.JobID), zap.Error(err))
+	}
+
+	data, err := json.Marshal(queuedJob{
+		JobID:    retry.JobID,
+		SiteName: retry.SiteName,
+		URL:      retry.URL,
+		QueuedAt: due,
+		Trigger:  retry.Trigger,
+		Attempt:  retry.Attempt,
+		RetryOf:  retry.RetryOf,
+	})
+	if err != nil {
+		s.logger.Error("failed to marshal retry", zap.String("job_id", job.JobID), zap.Error(err))
+		return
+	}
+
+	if err := s.redis.ZAdd(ctx, retriesKey, redis.Z{Score: float64(due.Unix()), Member: data}).Err(); err != nil {
+		s.logger.Error("failed to schedule retry", zap.String("job_id", job.JobID), zap.Error(err))
+		return
+	}
+
+	s.logger.Info("job retry scheduled",
+		zap.String("site", job.SiteName),
+		zap.String("job_id", retry.JobID),
+		zap.String("retry_of", retry.RetryOf),
+		zap.Int("attempt", retry.Attempt),
+		zap.Duration("delay", delay))
+}
+
+func (s *Scheduler) deadLetter(ctx context.Context, job *model.JobStatus, attempts int, runErr error) {
+	data, err := json.Marshal(DeadLetter{
+		JobID:    job.JobID,
+		SiteName: job.SiteName,
+		URL:      job.URL,
+		Trigger:  job.Trigger,
+		Attempts: attempts,
+		Error:    runErr.Error(),
+		FailedAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("failed to marshal dead letter", zap.String("job_id", job.JobID), zap.Error(err))
+		return
+	}
+
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, deadLetterKey, data)
+		pipe.LTrim(ctx, deadLetterKey, 0, maxDeadLetters-1)
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("failed to store dead letter", zap.String("job_id", job.JobID), zap.Error(err))
+		return
+	}
+
+	s.logger.Warn("job moved to dead-letter list",
+		zap.String("site", job.SiteName),
+		zap.String("job_id", job.JobID),
+		zap.Int("attempts", attempts))
+}
+
+// promoteRetries moves due retries to the queue until ctx is cancelled
+func (s *Scheduler) promoteRetries(ctx context.Context) {
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := s.promoteDue(ctx, time.Now())
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("failed to queue due retries", zap.Error(err))
+			}
+			continue
+		}
+		if n > 0 {
+			s.logger.Info("due retries queued", zap.Int("count", n))
+		}
+	}
+}
+
+// promoteDue moves the retries due at now whose site has no run waiting to
+// the queue and returns how many it moved
+func (s *Scheduler) promoteDue(ctx context.Context, now time.Time) (int, error) {
+	n, err := promoteScript.Run(ctx, s.redis,
+		[]string{retriesKey, queueKey},
+		now.Unix(), retryPromoteBatch, queuedKeyPrefix, int64(queuedKeyTTL.Seconds()),
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("promoting retries: %w", err)
+	}
+	return n, nil
+}
+
+// ListDeadLetters returns the runs in the dead-letter list, newest first
+func (s *Scheduler) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	entries, err := s.redis.LRange(ctx, deadLetterKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("loading dead letters: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(entries))
+	for _, data := range entries {
+		var dl DeadLetter
+		if err := json.Unmarshal([]byte(data), &dl); err != nil {
+			s.logger.Warn("skipping malformed dead letter", zap.String("entry", data), zap.Error(err))
+			continue
+		}
+		letters = append(letters, dl)
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter queues the run of a dead letter again, like a manual
+// trigger, and removes it from the list. The entry is taken off the list
+// first, so concurrent replays queue it once; it goes back if queueing fails.
+func (s *Scheduler) ReplayDeadLetter(ctx context.Context, jobID string) (*model.JobStatus, error) {
+	data, dl, err := s.findDeadLetter(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.redis.LRem(ctx, deadLetterKey, 1, data).Result()
+	if err != nil {
+		return nil, fmt.Errorf("removing dead letter: %w", err)
+	}
+	if removed == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	job, err := s.Enqueue(ctx, dl.SiteName, dl.URL)
+	if err != nil {
+		if pushErr := s.redis.LPush(context.WithoutCancel(ctx), deadLetterKey, data).Err(); pushErr != nil {
+			s.logger.Error("failed to restore dead letter",
+				zap.String("job_id", jobID),
+				zap.String("entry", data),
+				zap.Error(pushErr))
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// DeleteDeadLetter removes a run from the dead-letter list
+func (s *Scheduler) DeleteDeadLetter(ctx context.Context, jobID string) error {
+	data, _, err := s.findDeadLetter(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.redis.LRem(ctx, deadLetterKey, 1, data).Err(); err != nil {
+		return fmt.Errorf("deleting dead letter: %w", err)
+	}
+	return nil
+}
+
+// findDeadLetter returns the stored entry of a dead letter and its decoded form
+func (s *Scheduler) findDeadLetter(ctx context.Context, jobID string) (string, *DeadLetter, error) {
+	entries, err := s.redis.LRange(ctx, deadLetterKey, 0, -1).Result()
+	if err != nil {
+		return "", nil, fmt.Errorf("loading dead letters: %w", err)
+	}
+
+	for _, data := range entries {
+		var dl DeadLetter
+		if err := json.Unmarshal([]byte(data), &dl); err != nil {
+			continue
+		}
+		if dl.JobID == jobID {
+			return data, &dl, nil
+		}
+	}
+	return "", nil, ErrDeadLetterNotFound
+}
@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, Backoff: time.Minute, MaxBackoff: 5 * time.Minute}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+		ok      bool
+	}{
+		{attempt: 1, want: time.Minute, ok: true},
+		{attempt: 2, want: 2 * time.Minute, ok: true},
+		{attempt: 3, want: 4 * time.Minute, ok: true},
+		{attempt: 4, want: 5 * time.Minute, ok: true},
+		{attempt: 5, ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := policy.delay(tt.attempt)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("attempt %d: expected %v/%v, got %v/%v", tt.attempt, tt.want, tt.ok, got, ok)
+		}
+	}
+
+	if _, ok := (RetryPolicy{}).delay(1); ok {
+		t.Error("expected no retry without attempts")
+	}
+	if got, _ := (RetryPolicy{MaxAttempts: 1}).delay(1); got != defaultRetryBackoff {
+		t.Errorf("expected default backoff, got %v", got)
+	}
+}
+
+func TestRetryOrDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, svc := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{})
+	s.SetSiteOptions("site-a", SiteOptions{Retry: RetryPolicy{MaxAttempts: 1, Backoff: time.Minute}})
+
+	failed := svc.NewJob("site-a", "https://example.com/a", model.JobTriggerScheduled)
+	s.retryOrDeadLetter(ctx, failed, errors.New("timeout"))
+
+	retries, _ := mr.ZMembers(retriesKey)
+	if len(retries) != 1 {
+		t.Fatalf("expected one retry, got %v", retries)
+	}
+	var retry queuedJob
+	if err := json.Unmarshal([]byte(retries[0]), &retry); err != nil {
+		t.Fatalf("decoding retry: %v", err)
+	}
+	if retry.Attempt != 2 || retry.RetryOf != failed.JobID || retry.Trigger != model.JobTriggerRetry {
+		t.Fatalf("expected attempt 2 retrying %s, got %+v", failed.JobID, retry)
+	}
+	if score, _ := mr.ZScore(retriesKey, retries[0]); time.Until(time.Unix(int64(score), 0)) < 50*time.Second {
+		t.Fatalf("expected the retry to be due in a minute, got score %v", score)
+	}
+
+	// The retry fails too and uses up the policy
+	s.retryOrDeadLetter(ctx, &model.JobStatus{
+		JobID:    retry.JobID,
+		SiteName: "site-a",
+		Trigger:  model.JobTriggerRetry,
+		Attempt:  retry.Attempt,
+		RetryOf:  retry.RetryOf,
+	}, errors.New("timeout again"))
+
+	letters, err := s.ListDeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("listing dead letters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].JobID != retry.JobID || letters[0].Attempts != 2 || letters[0].Error != "timeout again" {
+		t.Fatalf("expected the retry in the dead-letter list, got %+v", letters)
+	}
+	if retries, _ := mr.ZMembers(retriesKey); len(retries) != 1 {
+		t.Fatalf("expected no further retry, got %v", retries)
+	}
+}
+
+func TestPromoteDue(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{}, "site-a", "site-b")
+
+	retry := func(jobID, site string, due time.Time) string {
+		data, _ := json.Marshal(queuedJob{JobID: jobID, SiteName: site, Trigger: model.JobTriggerRetry, Attempt: 2})
+		mr.ZAdd(retriesKey, float64(due.Unix()), string(data))
+		return string(data)
+	}
+
+	now := time.Now()
+	due := retry("job-due", "site-a", now.Add(-time.Second))
+	later := retry("job-later", "site-a", now.Add(time.Minute))
+
+	// site-b already has a manual trigger waiting
+	manual, err := s.Enqueue(ctx, "site-b", "https://example.com/b")
+	if err != nil {
+		t.Fatalf("enqueueing site-b: %v", err)
+	}
+	blocked := retry("job-blocked", "site-b", now.Add(-time.Second))
+
+	n, err := s.promoteDue(ctx, now)
+	if err != nil {
+		t.Fatalf("promoting retries: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected one retry promoted, got %d", n)
+	}
+	if queue, _ := mr.List(queueKey); len(queue) != 2 || queue[0] != due {
+		t.Fatalf("expected the due retry next to the manual trigger, got %v", queue)
+	}
+	if slot, _ := mr.Get(queuedKeyPrefix + "site-a"); slot != "job-due" {
+		t.Fatalf("expected the promoted retry to hold the queued slot, got %q", slot)
+	}
+	if slot, _ := mr.Get(queuedKeyPrefix + "site-b"); slot != manual.JobID {
+		t.Fatalf("expected the manual trigger to keep the queued slot, got %q", slot)
+	}
+	retries, _ := mr.ZMembers(retriesKey)
+	if len(retries) != 2 || !slices.Contains(retries, later) || !slices.Contains(retries, blocked) {
+		t.Fatalf("expected the later and blocked retries to wait, got %v", retries)
+	}
+
+	// A manual trigger of site-a is rejected while the retry waits
+	if _, err := s.Enqueue(ctx, "site-a", "https://example.com/a"); !errors.Is(err, ErrAlreadyQueued) {
+		t.Fatalf("expected ErrAlreadyQueued for site-a, got %v", err)
+	}
+}
+
+// pushDeadLetter stores a dead letter for jobID and returns its entry
+func pushDeadLetter(t *testing.T, mr *miniredis.Miniredis, jobID, site string) string {
+	t.Helper()
+	data, err := json.Marshal(DeadLetter{JobID: jobID, SiteName: site, URL: "https://example.com/" + site, Attempts: 3})
+	if err != nil {
+		t.Fatalf("encoding dead letter: %v", err)
+	}
+	mr.Lpush(deadLetterKey, string(data))
+	return string(data)
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{}, "site-a", "site-b")
+
+	pushDeadLetter(t, mr, "job-a", "site-a")
+	pushDeadLetter(t, mr, "job-b", "site-b")
+
+	job, err := s.ReplayDeadLetter(ctx, "job-a")
+	if err != nil {
+		t.Fatalf("replaying dead letter: %v", err)
+	}
+	if job.SiteName != "site-a" || job.Trigger != model.JobTriggerManual {
+		t.Fatalf("expected a manual run of site-a, got %+v", job)
+	}
+	if queue, _ := mr.List(queueKey); len(queue) != 1 {
+		t.Fatalf("expected the replay in the queue, got %v", queue)
+	}
+	if _, err := s.ReplayDeadLetter(ctx, "job-a"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected a replayed dead letter to be gone, got %v", err)
+	}
+
+	// A replay that cannot be queued leaves the dead letter in place
+	mr.Set(queuedKeyPrefix+"site-b", "job-other")
+	if _, err := s.ReplayDeadLetter(ctx, "job-b"); !errors.Is(err, ErrAlreadyQueued) {
+		t.Fatalf("expected ErrAlreadyQueued, got %v", err)
+	}
+	letters, _ := s.ListDeadLetters(ctx)
+	if len(letters) != 1 || letters[0].JobID != "job-b" {
+		t.Fatalf("expected job-b back in the dead-letter list, got %+v", letters)
+	}
+	if queue, _ := mr.List(queueKey); len(queue) != 1 {
+		t.Fatalf("expected no second queue entry, got %v", queue)
+	}
+}
+
+func TestDeleteDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{})
+
+	pushDeadLetter(t, mr, "job-a", "site-a")
+	kept := pushDeadLetter(t, mr, "job-b", "site-b")
+
+	if err := s.DeleteDeadLetter(ctx, "job-a"); err != nil {
+		t.Fatalf("deleting dead letter: %v", err)
+	}
+	if err := s.DeleteDeadLetter(ctx, "job-a"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+	if entries, _ := mr.List(deadLetterKey); len(entries) != 1 || entries[0] != kept {
+		t.Fatalf("expected only job-b to remain, got %v", entries)
+	}
+}
@@ -2,9 +2,17 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/service"
+	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
 	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
@@ -16,19 +24,150 @@ type Scheduler struct {
 	service  ScraperService
 	redis    *redis.Client
 	workerID string
+	version  string
 	logger   *zap.Logger
-	jobs     map[string]cron.EntryID
+
+	// startedAt is when Start was called, as published in the worker registry
+	startedAt time.Time
+
+	// catchUp is how long after a missed run a starting worker still runs it
+	catchUp time.Duration
+
+	// election turns on leader election; leader is set while this worker
+	// holds the leadership, and leaderRenewed, used by the election loop
+	// only, is when it last took or renewed it
+	election      bool
+	leader        atomic.Bool
+	leaderRenewed time.Time
+
+	// jobs holds the schedules applied to the local cron, by site
+	mu   sync.Mutex
+	jobs map[string]*scheduledSite
+
+	// sites holds per-site run options
+	sites map[string]SiteOptions
+
+	// adaptive holds the effective interval of sites with an adaptive policy
+	adaptive map[string]*adaptiveState
+
+	// running holds the jobs running on this worker, by job ID
+	running map[string]*runningJob
+
+	// exec runs every job of this worker on a bounded pool
+	exec *executor
+
+	// requeued signals the queue consumer that an entry went back to the
+	// queue because its site was locked
+	requeued chan struct{}
+
+	// ctx is the parent of every job and background loop started by Start;
+	// cancel stops them with service.ErrJobInterrupted
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+}
+
+// SiteOptions holds the per-site settings of scheduled and queued runs
+type SiteOptions struct {
+	// MaxDuration cancels a run that takes longer; zero means unlimited
+	MaxDuration time.Duration
+
+	// Location is the time zone of the schedule and quiet hours; nil means
+	// the host time zone
+	Location *time.Location
+
+	// Jitter delays every scheduled start by a random duration up to Jitter
+	Jitter time.Duration
+
+	// Retry is the retry policy of failed scheduled runs
+	Retry RetryPolicy
+
+	// Adaptive derives the interval between scheduled runs from churn
+	Adaptive AdaptivePolicy
+
+	// quietHours and holidays are when the site is never crawled
+	quietHours []timeWindow
+	holidays   map[string]bool
+}
+
+// SiteOptionsFromConfig returns the run options configured for a site, with
+// the scheduler-wide time zone and holiday list as defaults
+func SiteOptionsFromConfig(cfg *config.SiteConfig, defaults config.SchedulerConfig) (SiteOptions, error) {
+	opts := SiteOptions{
+		MaxDuration: time.Duration(cfg.MaxDuration) * time.Second,
+		Jitter:      time.Duration(cfg.Jitter) * time.Second,
+		Retry: RetryPolicy{
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			Backoff:     time.Duration(cfg.Retry.Backoff) * time.Second,
+			MaxBackoff:  time.Duration(cfg.Retry.MaxBackoff) * time.Second,
+		},
+		Adaptive: AdaptivePolicy{
+			MinInterval: time.Duration(cfg.Adaptive.MinInterval) * time.Second,
+			MaxInterval: time.Duration(cfg.Adaptive.MaxInterval) * time.Second,
+			Runs:        cfg.Adaptive.Runs,
+			TargetChurn: cfg.Adaptive.TargetChurn,
+		},
+	}
+
+	tz := cfg.Timezone
+	if tz == "" {
+		tz = defaults.Timezone
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return SiteOptions{}, fmt.Errorf("site %s: timezone: %w", cfg.Name, err)
+		}
+		opts.Location = loc
+	}
+
+	for _, v := range cfg.QuietHours {
+		w, err := parseTimeWindow(v)
+		if err != nil {
+			return SiteOptions{}, fmt.Errorf("site %s: %w", cfg.Name, err)
+		}
+		opts.quietHours = append(opts.quietHours, w)
+	}
+
+	if cfg.SkipHolidays {
+		opts.holidays = make(map[string]bool, len(defaults.Holidays))
+		for _, day := range defaults.Holidays {
+			if _, err := time.Parse(holidayLayout, day); err != nil {
+				return SiteOptions{}, fmt.Errorf("holiday %q: %w", day, err)
+			}
+			opts.holidays[day] = true
+		}
+	}
+
+	return opts, nil
+}
+
+// scheduledSite is a schedule applied to the local cron; entryID is zero
+// while the schedule is paused
+type scheduledSite struct {
+	schedule Schedule
+	entryID  cron.EntryID
 }
 
 // ScraperService defines the interface for scraping operations
 type ScraperService interface {
-	ScrapeWebsite(ctx context.Context, siteName, url string) error
+	NewJob(siteName, url, trigger string) *model.JobStatus
+	QueueJob(ctx context.Context, job *model.JobStatus, queuedAt time.Time) error
+	RunJob(ctx context.Context, job *model.JobStatus) error
+	AbortJob(ctx context.Context, job *model.JobStatus, err error)
+	ListJobs(ctx context.Context, filter *storage.RunFilter) ([]*model.JobStatus, error)
 }
 
 // New creates a new scheduler
-func New(service ScraperService, redis *redis.Client, workerID string, logger *zap.Logger) *Scheduler {
+func New(
+	service ScraperService,
+	redis *redis.Client,
+	workerID string,
+	cfg config.SchedulerConfig,
+	logger *zap.Logger,
+) *Scheduler {
 	c := cron.New(
-		cron.WithSeconds(),
+		cron.WithParser(specParser),
 		cron.WithLogger(newCronLogger(logger)),
 		cron.WithChain(
 			cron.SkipIfStillRunning(newCronLogger(logger)),
@@ -42,133 +181,302 @@ func New(service ScraperService, redis *redis.Client, workerID string, logger *z
 		redis:    redis,
 		workerID: workerID,
 		logger:   logger,
-		jobs:     make(map[string]cron.EntryID),
+		catchUp:  time.Duration(cfg.CatchUpWindow) * time.Second,
+		election: cfg.LeaderElection,
+		jobs:     make(map[string]*scheduledSite),
+		sites:    make(map[string]SiteOptions),
+		adaptive: make(map[string]*adaptiveState),
+		running:  make(map[string]*runningJob),
+		exec:     newExecutor(cfg.Workers),
+		requeued: make(chan struct{}, 1),
 	}
 }
 
-// AddJob adds a new scheduled job
+// SetVersion sets the build version this worker publishes in the worker registry
+func (s *Scheduler) SetVersion(version string) {
+	s.version = version
+}
+
+// SetSiteOptions sets the run options of a site
+func (s *Scheduler) SetSiteOptions(siteName string, opts SiteOptions) {
+	s.mu.Lock()
+	s.sites[siteName] = opts
+	s.mu.Unlock()
+}
+
+// AddJob registers the configured schedule of a site. Changes made through
+// the API are kept until the configured schedule itself changes.
 func (s *Scheduler) AddJob(siteName, schedule, url string) error {
-	entryID, err := s.cron.AddFunc(schedule, func() {
-		s.executeJob(siteName, url)
-	})
+	if _, err := specParser.Parse(schedule); err != nil {
+		return fmt.Errorf("adding job for %s: %w: %v", siteName, ErrInvalidSchedule, err)
+	}
 
+	outcome, err := s.seedSchedule(context.Background(), siteName, schedule, url)
 	if err != nil {
 		return fmt.Errorf("adding job for %s: %w", siteName, err)
 	}
 
-	s.jobs[siteName] = entryID
-
-	s.logger.Info("job scheduled",
-		zap.String("site", siteName),
-		zap.String("schedule", schedule),
-		zap.String("url", url))
-
+	switch outcome {
+	case seedKept:
+		s.logger.Info("keeping stored schedule", zap.String("site", siteName))
+	case seedReplaced:
+		s.logger.Info("stored schedule replaced by configured schedule",
+			zap.String("site", siteName),
+			zap.String("schedule", schedule))
+	}
 	return nil
 }
 
-// Start starts the scheduler
+// Start applies the stored schedules and starts the scheduler, the consumer
+// of queued scrapes and the watcher of schedule changes. With leader election
+// it also starts campaigning for the leadership.
 func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.ctx = ctx
+	s.cancel = cancel
+	s.startedAt = time.Now()
+
+	if err := s.syncSchedules(ctx); err != nil {
+		s.logger.Error("failed to load schedules", zap.Error(err))
+	}
+	s.loadAdaptive(ctx)
 	s.cron.Start()
+
+	// Register before consuming, so that other workers don't take this
+	// worker's processing list for a dead one's
+	if err := s.registerWorker(ctx); err != nil {
+		s.logger.Warn("failed to publish worker heartbeat", zap.Error(err))
+	}
+
+	s.wg.Add(5)
+	go func() {
+		defer s.wg.Done()
+		s.publishWorker(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.promoteRetries(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		if s.election {
+			s.elect(ctx)
+			return
+		}
+		s.catchUpMissed(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.consumeQueue(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.watch(ctx)
+	}()
+
+	s.mu.Lock()
+	count := len(s.jobs)
+	s.mu.Unlock()
+
 	s.logger.Info("scheduler started",
-		zap.Int("jobs", len(s.jobs)))
+		zap.Int("jobs", count),
+		zap.Int("pool_size", s.exec.size))
 }
 
-// Stop stops the scheduler gracefully
+// Stop stops the scheduler. Running jobs are cancelled and recorded as
+// interrupted and jobs still waiting for a slot are dropped; Stop waits for
+// that until ctx is done.
 func (s *Scheduler) Stop(ctx context.Context) {
 	s.logger.Info("stopping scheduler")
 	stopCtx := s.cron.Stop()
+	if s.cancel != nil {
+		s.cancel(service.ErrJobInterrupted)
+	}
+	s.exec.stop()
+
+	done := make(chan struct{})
+	go func() {
+		<-stopCtx.Done()
+		s.wg.Wait()
+		s.exec.wait()
+		close(done)
+	}()
 
 	// Wait for all jobs to complete or context timeout
 	select {
-	case <-stopCtx.Done():
+	case <-done:
 		s.logger.Info("all jobs completed")
 	case <-ctx.Done():
 		s.logger.Warn("scheduler stop timeout, forcing shutdown")
 	}
 }
 
-// executeJob executes a scraping job with distributed locking
+func (s *Scheduler) siteOptions(siteName string) SiteOptions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sites[siteName]
+}
+
+// executeJob executes a scheduled scraping job once the executor has a slot
+// for it. It returns when the job is done, so a site whose previous run is
+// still running or waiting skips the next tick. With leader election only the
+// leader fires schedules, and it pushes the job onto the shared queue instead.
 func (s *Scheduler) executeJob(siteName, url string) {
-	ctx := context.Background()
-	lockKey := fmt.Sprintf("job:lock:%s", siteName)
-	lockExpiry := 10 * time.Minute
+	if !s.drivesSchedules() {
+		return
+	}
+
+	opts := s.siteOptions(siteName)
+	if opts.Adaptive.enabled() && !s.adaptiveDue(s.ctx, siteName, opts, time.Now()) {
+		s.logger.Debug("skipping scheduled run, crawl interval not reached", zap.String("site", siteName))
+		return
+	}
 
 	s.logger.Info("job triggered",
 		zap.String("site", siteName),
 		zap.String("worker", s.workerID))
 
-	// Try to acquire lock
-	acquired, err := s.acquireLock(ctx, lockKey, lockExpiry)
-	if err != nil {
-		s.logger.Error("failed to acquire lock",
-			zap.String("site", siteName),
+	if opts.Jitter > 0 {
+		sleep(s.ctx, rand.N(opts.Jitter))
+		if s.ctx.Err() != nil || !s.drivesSchedules() {
+			return
+		}
+	}
+
+	if s.election {
+		if opts.quietAt(time.Now()) {
+			s.logger.Info("skipping scheduled run in quiet hours", zap.String("site", siteName))
+			return
+		}
+		s.dispatch(s.ctx, s.service.NewJob(siteName, url, model.JobTriggerScheduled))
+		return
+	}
+
+	task := s.exec.submit(s.ctx, siteName, model.JobTriggerScheduled, time.Now(), func(ctx context.Context) {
+		if s.siteOptions(siteName).quietAt(time.Now()) {
+			s.logger.Info("skipping scheduled run in quiet hours", zap.String("site", siteName))
+			return
+		}
+
+		job := s.service.NewJob(siteName, url, model.JobTriggerScheduled)
+		if _, err := s.runLocked(ctx, job, nil); err != nil {
+			s.logger.Error("failed to acquire lock",
+				zap.String("site", siteName),
+				zap.Error(err))
+		}
+	})
+	<-task.done
+}
+
+// dispatch pushes a scheduled or caught-up job onto the shared queue. A site
+// that already has a run waiting there skips it.
+func (s *Scheduler) dispatch(ctx context.Context, job *model.JobStatus) {
+	err := s.enqueue(ctx, job)
+	if errors.Is(err, ErrAlreadyQueued) {
+		s.logger.Info("skipping run, site already queued",
+			zap.String("site", job.SiteName),
+			zap.String("trigger", job.Trigger),
 			zap.Error(err))
 		return
 	}
+	if err != nil {
+		s.logger.Error("failed to dispatch job",
+			zap.String("site", job.SiteName),
+			zap.String("trigger", job.Trigger),
+			zap.Error(err))
+	}
+}
+
+// runLocked runs job under the per-site distributed lock, calling onStart, if
+// set, once the lock is held. It reports false without running the job if
+// another worker holds the lock. The lock lease is renewed while the job runs
+// and the job is cancelled with ErrLeaseLost if the lease cannot be kept.
+func (s *Scheduler) runLocked(ctx context.Context, job *model.JobStatus, onStart func()) (bool, error) {
+	siteName := job.SiteName
+
+	// Try to acquire lock
+	lease, err := s.acquireLock(ctx, siteName)
+	if err != nil {
+		return false, err
+	}
 
-	if !acquired {
+	if lease == nil {
 		s.logger.Info("job already running on another worker",
 			zap.String("site", siteName))
-		return
+		return false, nil
 	}
 
-	// Ensure lock is released
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	s.trackJob(job, lease.token, cancel)
+
+	if d := s.siteOptions(siteName).MaxDuration; d > 0 {
+		var cancelTimeout context.CancelFunc
+		jobCtx, cancelTimeout = context.WithTimeoutCause(jobCtx, d, ErrMaxDuration)
+		defer cancelTimeout()
+	}
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		s.heartbeat(jobCtx, lease, siteName, job.JobID, cancel)
+	}()
+
+	// Ensure the heartbeat stops and the lock is released
 	defer func() {
-		if err := s.releaseLock(ctx, lockKey); err != nil {
+		s.untrackJob(job.JobID)
+		cancel(nil)
+		<-heartbeatDone
+		if err := s.releaseLock(context.WithoutCancel(ctx), lease); err != nil {
 			s.logger.Error("failed to release lock",
 				zap.String("site", siteName),
 				zap.Error(err))
 		}
 	}()
 
+	if onStart != nil {
+		onStart()
+	}
+
+	job.FenceToken = lease.token
+
 	// Execute scraping
 	s.logger.Info("job started",
 		zap.String("site", siteName),
-		zap.String("worker", s.workerID))
+		zap.String("job_id", job.JobID),
+		zap.String("trigger", job.Trigger),
+		zap.String("worker", s.workerID),
+		zap.Int64("fence_token", lease.token))
 
 	startTime := time.Now()
 
-	err = s.service.ScrapeWebsite(ctx, siteName, url)
+	err = s.service.RunJob(jobCtx, job)
 	duration := time.Since(startTime)
 
 	if err != nil {
 		s.logger.Error("job failed",
 			zap.String("site", siteName),
+			zap.String("job_id", job.JobID),
+			zap.Int("attempt", job.Attempt),
 			zap.Duration("duration", duration),
 			zap.Error(err))
-		return
+		if job.Status == model.JobStatusFailed && job.Trigger != model.JobTriggerManual {
+			s.retryOrDeadLetter(context.WithoutCancel(ctx), job, err)
+		}
+		return true, nil
 	}
 
 	s.logger.Info("job completed",
 		zap.String("site", siteName),
-		zap.Duration("duration", duration))
-}
-
-// acquireLock acquires a distributed lock
-func (s *Scheduler) acquireLock(ctx context.Context, key string, expiry time.Duration) (bool, error) {
-	result, err := s.redis.SetNX(ctx, key, s.workerID, expiry).Result()
-	if err != nil {
-		return false, fmt.Errorf("acquiring lock: %w", err)
-	}
-	return result, nil
-}
+		zap.String("job_id", job.JobID),
+		zap.Duration("duration", duration),
+		zap.Int("pages", len(job.Pages)),
+		zap.Int("scraped", job.Scraped),
+		zap.Int("saved", job.Saved))
 
-// releaseLock releases a distributed lock
-func (s *Scheduler) releaseLock(ctx context.Context, key string) error {
-	// Only release if this worker holds the lock
-	val, err := s.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil // Lock already released
-	}
-	if err != nil {
-		return fmt.Errorf("checking lock: %w", err)
+	if job.Trigger != model.JobTriggerManual {
+		s.recordSuccess(context.WithoutCancel(ctx), siteName, job.StartTime)
+		s.noteAdaptiveRun(context.WithoutCancel(ctx), siteName, job.StartTime)
 	}
 
-	if val == s.workerID {
-		if err := s.redis.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("releasing lock: %w", err)
-		}
-	}
-
-	return nil
+	return true, nil
 }
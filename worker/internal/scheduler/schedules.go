@@ -0,0 +1,403 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// Redis keys of the shared schedule state. The hash holds the desired
+// schedule of every site; a site name published on the channel tells every
+// worker to reload that site's schedule.
+const (
+	schedulesKey     = "scheduler:schedules"
+	schedulesChannel = "scheduler:schedules:changed"
+)
+
+// scheduleResyncInterval is how often every worker reloads all schedules, so
+// a change missed while disconnected from pub/sub is still picked up
+const scheduleResyncInterval = time.Minute
+
+// specParser parses schedules with an optional leading seconds field
+var specParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Schedule errors
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+)
+
+// Schedule is the desired schedule of a site, shared by all workers
+type Schedule struct {
+	SiteName  string    `json:"site_name"`
+	Spec      string    `json:"schedule"`
+	URL       string    `json:"url,omitempty"`
+	Paused    bool      `json:"paused"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// SeedSpec and SeedURL are the configured schedule the entry was seeded
+	// from; a worker configured differently replaces the entry on start
+	SeedSpec string `json:"seed_schedule,omitempty"`
+	SeedURL  string `json:"seed_url,omitempty"`
+
+	// Deleted marks a schedule removed through the API. The entry is kept so
+	// the unchanged configured schedule is not seeded again.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Outcomes of seeding a configured schedule
+const (
+	seedAdded    = "added"
+	seedReplaced = "replaced"
+	seedKept     = "kept"
+)
+
+// ScheduleInfo is a schedule as applied on this worker
+type ScheduleInfo struct {
+	Schedule
+	Next     *time.Time    `json:"next_run,omitempty"`
+	Prev     *time.Time    `json:"prev_run,omitempty"`
+	Adaptive *AdaptiveInfo `json:"adaptive,omitempty"`
+}
+
+// ListSchedules returns the schedules applied on this worker, by site name
+func (s *Scheduler) ListSchedules() []ScheduleInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]ScheduleInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		infos = append(infos, s.scheduleInfo(job))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].SiteName < infos[j].SiteName })
+	return infos
+}
+
+// GetSchedule returns the schedule of a site as applied on this worker
+func (s *Scheduler) GetSchedule(siteName string) (*ScheduleInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[siteName]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	info := s.scheduleInfo(job)
+	return &info, nil
+}
+
+// PutSchedule adds or replaces the schedule of a site on all workers. A
+// paused schedule stays paused.
+func (s *Scheduler) PutSchedule(ctx context.Context, siteName, spec, url string) (*Schedule, error) {
+	if _, err := specParser.Parse(spec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	return s.updateSchedule(ctx, siteName, true, func(sch *Schedule) {
+		sch.Spec = spec
+		sch.URL = url
+	})
+}
+
+// PauseSchedule stops scheduled runs of a site on all workers
+func (s *Scheduler) PauseSchedule(ctx context.Context, siteName string) (*Schedule, error) {
+	return s.updateSchedule(ctx, siteName, false, func(sch *Schedule) {
+		sch.Paused = true
+	})
+}
+
+// ResumeSchedule restarts scheduled runs of a paused site on all workers
+func (s *Scheduler) ResumeSchedule(ctx context.Context, siteName string) (*Schedule, error) {
+	return s.updateSchedule(ctx, siteName, false, func(sch *Schedule) {
+		sch.Paused = false
+	})
+}
+
+// DeleteSchedule removes the schedule of a site from all workers. It stays
+// removed across restarts until the configured schedule changes.
+func (s *Scheduler) DeleteSchedule(ctx context.Context, siteName string) error {
+	_, err := s.updateSchedule(ctx, siteName, false, func(sch *Schedule) {
+		sch.Deleted = true
+	})
+	return err
+}
+
+// updateSchedule applies change to the stored schedule of a site and
+// propagates the result. With create set a missing schedule is created.
+func (s *Scheduler) updateSchedule(ctx context.Context, siteName string, create bool, change func(*Schedule)) (*Schedule, error) {
+	sch, err := s.loadSchedule(ctx, siteName)
+	if err != nil {
+		return nil, err
+	}
+	if sch == nil || sch.Deleted {
+		if !create {
+			return nil, ErrScheduleNotFound
+		}
+
+		// A schedule recreated after a deletion keeps the configuration it
+		// was seeded from
+		fresh := &Schedule{SiteName: siteName}
+		if sch != nil {
+			fresh.SeedSpec, fresh.SeedURL = sch.SeedSpec, sch.SeedURL
+		}
+		sch = fresh
+	}
+
+	change(sch)
+	sch.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(sch)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling schedule: %w", err)
+	}
+	if err := s.redis.HSet(ctx, schedulesKey, siteName, data).Err(); err != nil {
+		return nil, fmt.Errorf("storing schedule: %w", err)
+	}
+
+	s.publishChange(ctx, siteName)
+	return sch, nil
+}
+
+// publishChange tells every worker to reload the schedule of a site. The
+// local cron is updated right away so the caller sees its own change.
+func (s *Scheduler) publishChange(ctx context.Context, siteName string) {
+	if err := s.reloadSchedule(ctx, siteName); err != nil {
+		s.logger.Error("failed to apply schedule", zap.String("site", siteName), zap.Error(err))
+	}
+	if err := s.redis.Publish(ctx, schedulesChannel, siteName).Err(); err != nil {
+		s.logger.Error("failed to publish schedule change",
+			zap.String("site", siteName),
+			zap.Error(err))
+	}
+}
+
+// seedSchedule stores the configured schedule of a site. A stored schedule
+// seeded from the same configuration is kept with the changes made through
+// the API, including a deletion; one seeded from a different configuration,
+// or stored before seeds were recorded, is replaced and only stays paused.
+func (s *Scheduler) seedSchedule(ctx context.Context, siteName, spec, url string) (string, error) {
+	stored, err := s.loadSchedule(ctx, siteName)
+	if err != nil {
+		return "", err
+	}
+	if stored != nil && stored.SeedSpec == spec && stored.SeedURL == url {
+		return seedKept, nil
+	}
+
+	sch := Schedule{
+		SiteName:  siteName,
+		Spec:      spec,
+		URL:       url,
+		UpdatedAt: time.Now(),
+		SeedSpec:  spec,
+		SeedURL:   url,
+	}
+	outcome := seedAdded
+	if stored != nil {
+		sch.Paused = stored.Paused && !stored.Deleted
+		outcome = seedReplaced
+	}
+
+	data, err := json.Marshal(sch)
+	if err != nil {
+		return "", fmt.Errorf("marshaling schedule: %w", err)
+	}
+	if err := s.redis.HSet(ctx, schedulesKey, siteName, data).Err(); err != nil {
+		return "", fmt.Errorf("storing schedule: %w", err)
+	}
+	if outcome == seedReplaced {
+		s.publishChange(ctx, siteName)
+	}
+	return outcome, nil
+}
+
+func (s *Scheduler) loadSchedule(ctx context.Context, siteName string) (*Schedule, error) {
+	data, err := s.redis.HGet(ctx, schedulesKey, siteName).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading schedule: %w", err)
+	}
+
+	var sch Schedule
+	if err := json.Unmarshal([]byte(data), &sch); err != nil {
+		return nil, fmt.Errorf("decoding schedule of %s: %w", siteName, err)
+	}
+	return &sch, nil
+}
+
+// watch applies schedule changes and cancel requests published by any worker
+// until ctx is cancelled
+func (s *Scheduler) watch(ctx context.Context) {
+	pubsub := s.redis.Subscribe(ctx, schedulesChannel, cancelChannel)
+	defer pubsub.Close()
+
+	ticker := time.NewTicker(scheduleResyncInterval)
+	defer ticker.Stop()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if msg.Channel == cancelChannel {
+				s.cancelLocal(msg.Payload)
+				continue
+			}
+			if err := s.reloadSchedule(ctx, msg.Payload); err != nil {
+				s.logger.Error("failed to apply schedule change",
+					zap.String("site", msg.Payload),
+					zap.Error(err))
+			}
+		case <-ticker.C:
+			if err := s.syncSchedules(ctx); err != nil {
+				s.logger.Error("failed to sync schedules", zap.Error(err))
+			}
+		}
+	}
+}
+
+// syncSchedules makes the local cron match every stored schedule
+func (s *Scheduler) syncSchedules(ctx context.Context) error {
+	stored, err := s.redis.HGetAll(ctx, schedulesKey).Result()
+	if err != nil {
+		return fmt.Errorf("loading schedules: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	live := make(map[string]bool, len(stored))
+	for siteName, data := range stored {
+		var sch Schedule
+		if err := json.Unmarshal([]byte(data), &sch); err != nil {
+			s.logger.Error("skipping malformed schedule", zap.String("site", siteName), zap.Error(err))
+			continue
+		}
+		if sch.Deleted {
+			continue
+		}
+		live[siteName] = true
+		if err := s.applyLocked(sch); err != nil {
+			s.logger.Error("failed to apply schedule", zap.String("site", siteName), zap.Error(err))
+		}
+	}
+
+	for siteName := range s.jobs {
+		if !live[siteName] {
+			s.removeLocked(siteName)
+		}
+	}
+	return nil
+}
+
+// reloadSchedule makes the local cron match the stored schedule of a site
+func (s *Scheduler) reloadSchedule(ctx context.Context, siteName string) error {
+	sch, err := s.loadSchedule(ctx, siteName)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sch == nil || sch.Deleted {
+		s.removeLocked(siteName)
+		return nil
+	}
+	return s.applyLocked(*sch)
+}
+
+// applyLocked schedules sch on the local cron, replacing a previous schedule
+// of the site. The caller holds s.mu.
+func (s *Scheduler) applyLocked(sch Schedule) error {
+	// A site that is disabled or unknown in this worker's config has no
+	// scraper here, so its runs could only fail
+	if _, ok := s.sites[sch.SiteName]; !ok {
+		s.removeLocked(sch.SiteName)
+		return nil
+	}
+
+	if current, ok := s.jobs[sch.SiteName]; ok && current.schedule == sch {
+		return nil
+	}
+	s.removeLocked(sch.SiteName)
+
+	job := &scheduledSite{schedule: sch}
+	if !sch.Paused {
+		schedule, err := s.sites[sch.SiteName].parseSpec(sch.Spec)
+		if err != nil {
+			return err
+		}
+
+		siteName, url := sch.SiteName, sch.URL
+		job.entryID = s.cron.Schedule(schedule, cron.FuncJob(func() {
+			s.executeJob(siteName, url)
+		}))
+	}
+	s.jobs[sch.SiteName] = job
+
+	s.logger.Info("job scheduled",
+		zap.String("site", sch.SiteName),
+		zap.String("schedule", sch.Spec),
+		zap.String("url", sch.URL),
+		zap.Bool("paused", sch.Paused))
+	return nil
+}
+
+// removeLocked drops the schedule of a site from the local cron. The caller
+// holds s.mu.
+func (s *Scheduler) removeLocked(siteName string) {
+	job, ok := s.jobs[siteName]
+	if !ok {
+		return
+	}
+	if job.entryID != 0 {
+		s.cron.Remove(job.entryID)
+	}
+	delete(s.jobs, siteName)
+
+	s.logger.Info("job unscheduled", zap.String("site", siteName))
+}
+
+// scheduleInfo reports a local schedule with its run times and, for an
+// adaptive site, its effective interval. The caller holds s.mu.
+func (s *Scheduler) scheduleInfo(job *scheduledSite) ScheduleInfo {
+	info := ScheduleInfo{Schedule: job.schedule}
+	if job.entryID == 0 {
+		info.Adaptive, _ = s.adaptiveInfoLocked(job.schedule.SiteName, nil)
+		return info
+	}
+
+	entry := s.cron.Entry(job.entryID)
+	if !entry.Next.IsZero() {
+		next := entry.Next
+		info.Next = &next
+	}
+	if !entry.Prev.IsZero() {
+		prev := entry.Prev
+		info.Prev = &prev
+	}
+
+	// An adaptive site skips slots until its interval has passed
+	var next *time.Time
+	info.Adaptive, next = s.adaptiveInfoLocked(job.schedule.SiteName, entry.Schedule.Next)
+	if next != nil {
+		info.Next = next
+	}
+	return info
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+)
+
+func TestSeedSchedule(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{}, "rumah123")
+
+	load := func() *Schedule {
+		t.Helper()
+		sch, err := s.loadSchedule(ctx, "rumah123")
+		if err != nil {
+			t.Fatalf("loading schedule: %v", err)
+		}
+		return sch
+	}
+
+	if err := s.AddJob("rumah123", "0 0 2 * * *", "https://example.com/a"); err != nil {
+		t.Fatalf("adding job: %v", err)
+	}
+
+	// Changes made through the API survive a restart with the same config
+	if _, err := s.PutSchedule(ctx, "rumah123", "0 0 4 * * *", "https://example.com/a"); err != nil {
+		t.Fatalf("putting schedule: %v", err)
+	}
+	if _, err := s.PauseSchedule(ctx, "rumah123"); err != nil {
+		t.Fatalf("pausing schedule: %v", err)
+	}
+	if err := s.AddJob("rumah123", "0 0 2 * * *", "https://example.com/a"); err != nil {
+		t.Fatalf("adding job: %v", err)
+	}
+	if sch := load(); sch.Spec != "0 0 4 * * *" || !sch.Paused {
+		t.Fatalf("expected the API schedule to be kept, got %+v", sch)
+	}
+
+	// A changed configured schedule wins and keeps the pause
+	if err := s.AddJob("rumah123", "0 0 3 * * *", "https://example.com/a"); err != nil {
+		t.Fatalf("adding job: %v", err)
+	}
+	if sch := load(); sch.Spec != "0 0 3 * * *" || !sch.Paused {
+		t.Fatalf("expected the configured schedule, got %+v", sch)
+	}
+
+	// A deletion is not undone by a restart with the same config
+	if err := s.DeleteSchedule(ctx, "rumah123"); err != nil {
+		t.Fatalf("deleting schedule: %v", err)
+	}
+	if err := s.AddJob("rumah123", "0 0 3 * * *", "https://example.com/a"); err != nil {
+		t.Fatalf("adding job: %v", err)
+	}
+	if err := s.syncSchedules(ctx); err != nil {
+		t.Fatalf("syncing schedules: %v", err)
+	}
+	if _, err := s.GetSchedule("rumah123"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Fatalf("expected the schedule to stay deleted, got %v", err)
+	}
+	if err := s.DeleteSchedule(ctx, "rumah123"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Fatalf("expected a second delete to find nothing, got %v", err)
+	}
+
+	// A schedule put again after its deletion is applied
+	if _, err := s.PutSchedule(ctx, "rumah123", "0 0 5 * * *", ""); err != nil {
+		t.Fatalf("putting schedule: %v", err)
+	}
+	if _, err := s.GetSchedule("rumah123"); err != nil {
+		t.Fatalf("expected the schedule to be applied, got %v", err)
+	}
+}
+
+func TestSyncSchedulesSkipsUnknownSites(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	// Another worker seeded a site this worker has disabled
+	other, _ := newTestScheduler(t, mr, "worker-1", config.SchedulerConfig{}, "rumah123", "lamudi")
+	for _, site := range []string{"rumah123", "lamudi"} {
+		if err := other.AddJob(site, "0 0 2 * * *", ""); err != nil {
+			t.Fatalf("adding job: %v", err)
+		}
+	}
+
+	s, _ := newTestScheduler(t, mr, "worker-2", config.SchedulerConfig{}, "rumah123")
+	if err := s.syncSchedules(ctx); err != nil {
+		t.Fatalf("syncing schedules: %v", err)
+	}
+
+	infos := s.ListSchedules()
+	if len(infos) != 1 || infos[0].SiteName != "rumah123" {
+		t.Fatalf("expected only rumah123 to be scheduled, got %+v", infos)
+	}
+}
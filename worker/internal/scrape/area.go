@@ -0,0 +1,157 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// areaRe matches an area, or plot dimensions such as "10x15", with an
+	// optional unit. The trailing group keeps "are" from matching the start
+	// of a longer word.
+	areaRe = regexp.MustCompile(`(\d+(?:[.,]\d+)*)(?:\s*(?:x|×|\*)\s*(\d+(?:[.,]\d+)*))?\s*(m²|m2|m\^2|meter persegi|meter|mtr2|mtr|sqm|m|hektare|hektar|ha|are)?(?:[^a-z0-9²]|$)`)
+
+	// Labels of land and building areas
+	landLabelRe     = regexp.MustCompile(`\b(?:luas\s+tanah|l\.?\s?t\.?|tanah|land(?:\s+area)?)(?:[^a-z]|$)`)
+	buildingLabelRe = regexp.MustCompile(`\b(?:luas\s+bangunan|l\.?\s?b\.?|bangunan|building(?:\s+area)?)(?:[^a-z]|$)`)
+
+	// areaPairRe matches both areas under one label, e.g. "LT/LB: 120/90 m²"
+	areaPairRe = regexp.MustCompile(`\bl\.?\s?t\.?\s*/\s*l\.?\s?b\.?\s*:?\s*(\d+(?:[.,]\d+)*)\s*(?:m²|m2)?\s*/\s*(\d+(?:[.,]\d+)*)`)
+)
+
+// areaUnits maps area units to square metres
+var areaUnits = map[string]float64{
+	"": 1, "m²": 1, "m2": 1, "m^2": 1, "meter persegi": 1, "meter": 1, "mtr2": 1, "mtr": 1, "sqm": 1, "m": 1,
+	"are":     100,
+	"ha":      10000,
+	"hektar":  10000,
+	"hektare": 10000,
+}
+
+// Areas holds the areas found in a string in square metres. Unlabelled is
+// the first area without a land or building label.
+type Areas struct {
+	Land       float64
+	Building   float64
+	Unlabelled float64
+}
+
+// ParseAreas finds the land and building areas in s, which may hold both,
+// as in "LT 120 m² / LB 90 m²", "Luas Tanah: 1.200 m2", "LT/LB 120/90" or
+// "Tanah 0,5 ha". Plot dimensions such as "10x15" give their product.
+func ParseAreas(s string) Areas {
+	var areas Areas
+
+	text := strings.ToLower(CleanText(strings.ReplaceAll(s, "\u00a0", " ")))
+	if text == "" {
+		return areas
+	}
+
+	if m := areaPairRe.FindStringSubmatch(text); m != nil {
+		areas.Land, _ = parseNumber(m[1], false)
+		areas.Building, _ = parseNumber(m[2], false)
+		return areas
+	}
+
+	prevEnd := 0
+	for _, m := range areaRe.FindAllStringSubmatchIndex(text, -1) {
+		value, err := parseAreaMatch(text, m)
+		if err != nil || value <= 0 {
+			continue
+		}
+
+		// The label of an area stands between the previous area and this one
+		label := text[prevEnd:m[0]]
+		prevEnd = m[1]
+
+		switch {
+		case lastIndex(buildingLabelRe, label) > lastIndex(landLabelRe, label):
+			if areas.Building == 0 {
+				areas.Building = value
+			}
+		case lastIndex(landLabelRe, label) >= 0:
+			if areas.Land == 0 {
+				areas.Land = value
+			}
+		default:
+			if areas.Unlabelled == 0 {
+				areas.Unlabelled = value
+			}
+		}
+	}
+	return areas
+}
+
+// ParseArea parses a single area such as "1.200 m2", "0,5 ha", "3 are" or
+// "10x15" into square metres
+func ParseArea(s string) (float64, error) {
+	areas := ParseAreas(s)
+	for _, v := range []float64{areas.Unlabelled, areas.Land, areas.Building} {
+		if v > 0 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("no area found in: %s", s)
+}
+
+// parseAreaMatch converts an areaRe match to square metres
+func parseAreaMatch(text string, m []int) (float64, error) {
+	value, err := parseNumber(text[m[2]:m[3]], false)
+	if err != nil {
+		return 0, err
+	}
+
+	// Plot dimensions are in metres
+	if m[4] >= 0 {
+		depth, err := parseNumber(text[m[4]:m[5]], false)
+		if err != nil {
+			return 0, err
+		}
+		return value * depth, nil
+	}
+
+	var unit string
+	if m[6] >= 0 {
+		unit = text[m[6]:m[7]]
+	}
+	return value * areaUnits[unit], nil
+}
+
+// lastIndex returns where the last match of re in s starts, or -1
+func lastIndex(re *regexp.Regexp, s string) int {
+	all := re.FindAllStringIndex(s, -1)
+	if len(all) == 0 {
+		return -1
+	}
+	return all[len(all)-1][0]
+}
+
+// landAndBuildingArea reads the land and building areas from the text of
+// their selectors. Either text may hold both, as sites often render them in
+// one element, and an unlabelled area belongs to the selector it came from.
+func landAndBuildingArea(landText, buildingText string) (float64, float64) {
+	var land, building float64
+
+	if landText != "" {
+		a := ParseAreas(landText)
+		land, building = a.Land, a.Building
+		if land == 0 {
+			land = a.Unlabelled
+		}
+	}
+
+	if buildingText != "" {
+		a := ParseAreas(buildingText)
+		switch {
+		case a.Building > 0:
+			building = a.Building
+		case a.Unlabelled > 0 && buildingText != landText:
+			building = a.Unlabelled
+		}
+		if land == 0 {
+			land = a.Land
+		}
+	}
+	return land, building
+}
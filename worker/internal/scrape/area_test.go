@@ -0,0 +1,98 @@
+package scrape
+
+import "testing"
+
+func TestParseAreas(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Areas
+	}{
+		// Single areas
+		{input: "120 m²", want: Areas{Unlabelled: 120}},
+		{input: "120m2", want: Areas{Unlabelled: 120}},
+		{input: "1.200 m2", want: Areas{Unlabelled: 1200}},
+		{input: "1,250 m²", want: Areas{Unlabelled: 1250}},
+		{input: "72,5 m²", want: Areas{Unlabelled: 72.5}},
+		{input: "90 sqm", want: Areas{Unlabelled: 90}},
+		{input: "150 meter persegi", want: Areas{Unlabelled: 150}},
+		{input: "200", want: Areas{Unlabelled: 200}},
+
+		// Other units
+		{input: "0,5 ha", want: Areas{Unlabelled: 5000}},
+		{input: "2 Hektar", want: Areas{Unlabelled: 20000}},
+		{input: "1.5 hektare", want: Areas{Unlabelled: 15000}},
+		{input: "3 are", want: Areas{Unlabelled: 300}},
+
+		// Plot dimensions
+		{input: "10x15", want: Areas{Unlabelled: 150}},
+		{input: "8 x 12,5 m", want: Areas{Unlabelled: 100}},
+		{input: "6×20", want: Areas{Unlabelled: 120}},
+
+		// Labelled areas
+		{input: "LT 120 m²", want: Areas{Land: 120}},
+		{input: "LB: 90 m²", want: Areas{Building: 90}},
+		{input: "L.T. 200 m2", want: Areas{Land: 200}},
+		{input: "Luas Tanah: 1.200 m2", want: Areas{Land: 1200}},
+		{input: "Luas Bangunan 350 m²", want: Areas{Building: 350}},
+		{input: "Tanah 0,5 ha", want: Areas{Land: 5000}},
+		{input: "Land Area 300 sqm", want: Areas{Land: 300}},
+
+		// Both in one element
+		{input: "LT 120 m² / LB 90 m²", want: Areas{Land: 120, Building: 90}},
+		{input: "LT 120m² LB 90m²", want: Areas{Land: 120, Building: 90}},
+		{input: "LB 90 m² · LT 120 m²", want: Areas{Land: 120, Building: 90}},
+		{input: "Luas Tanah 10x15 Luas Bangunan 100 m2", want: Areas{Land: 150, Building: 100}},
+		{input: "LT/LB: 120/90 m²", want: Areas{Land: 120, Building: 90}},
+		{input: "LT/LB 1.000/450", want: Areas{Land: 1000, Building: 450}},
+		{input: "3 KT · 2 KM · LT 120 m²", want: Areas{Land: 120, Unlabelled: 3}},
+
+		// Nothing to find
+		{input: "", want: Areas{}},
+		{input: "Hubungi agen", want: Areas{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ParseAreas(tt.input); got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLandAndBuildingArea(t *testing.T) {
+	tests := []struct {
+		name         string
+		land         string
+		building     string
+		wantLand     float64
+		wantBuilding float64
+	}{
+		{name: "separate elements", land: "120 m²", building: "90 m²", wantLand: 120, wantBuilding: 90},
+		{name: "same element", land: "LT 120 m² / LB 90 m²", building: "LT 120 m² / LB 90 m²", wantLand: 120, wantBuilding: 90},
+		{name: "both in land element", land: "LT 120 m² / LB 90 m²", wantLand: 120, wantBuilding: 90},
+		{name: "both in building element", building: "LT 120 m² / LB 90 m²", wantLand: 120, wantBuilding: 90},
+		{name: "same unlabelled element", land: "120 m²", building: "120 m²", wantLand: 120},
+		{name: "building only", building: "LB 45 m²", wantBuilding: 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			land, building := landAndBuildingArea(tt.land, tt.building)
+			if land != tt.wantLand || building != tt.wantBuilding {
+				t.Fatalf("expected %v/%v, got %v/%v", tt.wantLand, tt.wantBuilding, land, building)
+			}
+		})
+	}
+}
+
+func TestParseArea(t *testing.T) {
+	got, err := ParseArea("0,5 ha")
+	if err != nil || got != 5000 {
+		t.Fatalf("expected 5000, got %v (%v)", got, err)
+	}
+
+	if _, err := ParseArea("Hubungi agen"); err == nil {
+		t.Fatal("expected an error for a string without area")
+	}
+}
@@ -9,15 +9,18 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/geo"
 	"github.com/Alwanly/Houses-Prices/worker/internal/model"
 	"github.com/Alwanly/Houses-Prices/worker/internal/pkg/retry"
 )
 
 // CollyScraper implements Scraper using Colly framework
 type CollyScraper struct {
-	config    *config.SiteConfig
-	collector *colly.Collector
-	logger    *zap.Logger
+	config          *config.SiteConfig
+	collector       *colly.Collector
+	detailCollector *colly.Collector
+	classifier      *PropertyClassifier
+	logger          *zap.Logger
 }
 
 // NewCollyScraper creates a new Colly-based scraper
@@ -25,6 +28,8 @@ func NewCollyScraper(cfg *config.SiteConfig, logger *zap.Logger) *CollyScraper {
 	c := colly.NewCollector(
 		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
 		colly.Async(true),
+		// Every run revisits the same search and detail pages
+		colly.AllowURLRevisit(),
 	)
 
 	// Set rate limiting
@@ -51,53 +56,121 @@ func NewCollyScraper(cfg *config.SiteConfig, logger *zap.Logger) *CollyScraper {
 			zap.String("url", r.URL.String()))
 	})
 
+	// Rules are checked when the config is loaded
+	classifier, err := NewPropertyClassifier(cfg.PropertyTypes)
+	if err != nil {
+		logger.Warn("ignoring property type rules", zap.String("site", cfg.Name), zap.Error(err))
+		classifier, _ = NewPropertyClassifier(nil)
+	}
+
 	return &CollyScraper{
-		config:    cfg,
-		collector: c,
-		logger:    logger,
+		config:          cfg,
+		collector:       c,
+		detailCollector: newDetailCollector(cfg),
+		classifier:      classifier,
+		logger:          logger,
 	}
 }
 
 // Scrape implements the Scraper interface
 func (s *CollyScraper) Scrape(ctx context.Context, url string) (*model.ScrapeResult, error) {
+	listings := make([]*model.Listing, 0)
+	result, err := s.ScrapeStream(ctx, url, func(listing *model.Listing) error {
+		listings = append(listings, listing)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Listings = listings
+	return result, nil
+}
+
+// ScrapeStream implements the StreamScraper interface. Colly runs the HTML
+// callbacks of a page sequentially, so onListing is never called concurrently
+// and blocking in it slows the crawl down.
+func (s *CollyScraper) ScrapeStream(
+	ctx context.Context,
+	url string,
+	onListing func(*model.Listing) error,
+	onProgress func(model.ScrapeProgress),
+) (*model.ScrapeResult, error) {
 	startTime := time.Now()
 
 	result := &model.ScrapeResult{
 		SiteName: s.config.Name,
 		URL:      url,
-		Listings: make([]*model.Listing, 0),
 		Errors:   make([]string, 0),
 	}
 
+	var abortErr error
+	progress := func(lastErr error, done bool) {
+		if onProgress == nil {
+			return
+		}
+		p := model.ScrapeProgress{
+			SiteName: s.config.Name,
+			URL:      url,
+			Scraped:  result.TotalScraped,
+			Errors:   result.ErrorCount,
+			Done:     done,
+		}
+		if lastErr != nil {
+			p.LastError = lastErr.Error()
+		}
+		onProgress(p)
+	}
+
 	// Clone collector for this scrape
 	c := s.collector.Clone()
 
+	// Stop issuing requests once the caller gives up
+	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+		}
+	})
+
 	// Extract listings
 	c.OnHTML(s.config.Selectors.ListItem, func(e *colly.HTMLElement) {
+		if abortErr != nil {
+			return
+		}
+
 		listing, err := s.extractListing(e)
 		if err != nil {
 			s.logger.Warn("failed to extract listing",
 				zap.Error(err))
 			result.Errors = append(result.Errors, err.Error())
 			result.ErrorCount++
+			progress(err, false)
 			return
 		}
 
-		if listing != nil {
-			result.Listings = append(result.Listings, listing)
-			result.TotalScraped++
+		if listing == nil {
+			return
 		}
-	})
 
-	// Extract next page URL
-	c.OnHTML(s.config.Selectors.NextPage, func(e *colly.HTMLElement) {
-		nextURL := e.Attr("href")
-		if nextURL != "" {
-			result.NextPageURL = MakeAbsoluteURL(url, nextURL)
-			result.HasNextPage = true
+		if err := onListing(listing); err != nil {
+			abortErr = err
+			return
 		}
+		result.TotalScraped++
+		progress(nil, false)
 	})
 
+	// Extract next page URL
+	if s.config.Selectors.NextPage != "" {
+		c.OnHTML(s.config.Selectors.NextPage, func(e *colly.HTMLElement) {
+			nextURL := e.Attr("href")
+			if nextURL != "" && !result.HasNextPage {
+				result.NextPageURL = e.Request.AbsoluteURL(nextURL)
+				result.HasNextPage = result.NextPageURL != ""
+			}
+		})
+	}
+
 	// Visit with retry
 	retryConfig := retry.DefaultConfig()
 	err := retry.Do(ctx, retryConfig, func() error {
@@ -111,8 +184,16 @@ func (s *CollyScraper) Scrape(ctx context.Context, url string) (*model.ScrapeRes
 	// Wait for async operations
 	c.Wait()
 
+	if abortErr != nil {
+		return nil, fmt.Errorf("handling listing: %w", abortErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	result.Duration = time.Since(startTime).Seconds()
 	result.TotalFound = result.TotalScraped
+	progress(nil, true)
 
 	s.logger.Info("scraping completed",
 		zap.String("site", s.config.Name),
@@ -135,10 +216,17 @@ func (s *CollyScraper) extractListing(e *colly.HTMLElement) (*model.Listing, err
 	}
 
 	priceText := CleanText(e.ChildText(sel.Price))
-	price, err := ParsePrice(priceText)
+	price, err := ParsePriceInfo(priceText)
 	if err != nil {
 		return nil, fmt.Errorf("parsing price: %w", err)
 	}
+	// A negotiable price without an amount ("Harga Nego") is stored as an
+	// unknown price of zero
+	if price.Amount <= 0 && !price.Negotiable {
+		return nil, fmt.Errorf("missing price amount in: %s", priceText)
+	}
+
+	listingType, rentPeriod := s.listingType(&price)
 
 	location := CleanText(e.ChildText(sel.Location))
 	if location == "" {
@@ -167,15 +255,14 @@ func (s *CollyScraper) extractListing(e *colly.HTMLElement) (*model.Listing, err
 		bathrooms = ParseInt(e.ChildText(sel.Bathrooms))
 	}
 
-	landArea := 0.0
+	var landText, buildingText string
 	if sel.LandArea != "" {
-		landArea = ParseFloat(e.ChildText(sel.LandArea))
+		landText = e.ChildText(sel.LandArea)
 	}
-
-	buildingArea := 0.0
 	if sel.BuildingArea != "" {
-		buildingArea = ParseFloat(e.ChildText(sel.BuildingArea))
+		buildingText = e.ChildText(sel.BuildingArea)
 	}
+	landArea, buildingArea := landAndBuildingArea(landText, buildingText)
 
 	description := ""
 	if sel.Description != "" {
@@ -192,6 +279,16 @@ func (s *CollyScraper) extractListing(e *colly.HTMLElement) (*model.Listing, err
 		agentPhone = CleanText(e.ChildText(sel.AgentPhone))
 	}
 
+	propertyLabel := ""
+	if sel.PropertyType != "" {
+		propertyLabel = CleanText(e.ChildText(sel.PropertyType))
+	}
+	propertyType, confidence := s.classifier.Classify(PropertySignals{
+		URLs:  []string{detailURL, e.Request.URL.String()},
+		Label: propertyLabel,
+		Title: title,
+	})
+
 	// Extract images
 	images := make([]string, 0)
 	if sel.Images != "" {
@@ -207,22 +304,60 @@ func (s *CollyScraper) extractListing(e *colly.HTMLElement) (*model.Listing, err
 	}
 
 	listing := &model.Listing{
-		SiteName:     s.config.Name,
-		URL:          detailURL,
-		Title:        title,
-		Price:        price,
-		Location:     location,
-		Bedrooms:     bedrooms,
-		Bathrooms:    bathrooms,
-		LandArea:     landArea,
-		BuildingArea: buildingArea,
-		Description:  description,
-		Images:       images,
-		AgentName:    agentName,
-		AgentPhone:   agentPhone,
-		ScrapedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		SiteName:               s.config.Name,
+		URL:                    detailURL,
+		Title:                  title,
+		Price:                  price.Amount,
+		PriceInfo:              &price,
+		ListingType:            listingType,
+		RentPeriod:             rentPeriod,
+		PropertyType:           propertyType,
+		PropertyTypeConfidence: confidence,
+		Location:               location,
+		Address:                s.normalizeLocation(location),
+		Bedrooms:               bedrooms,
+		Bathrooms:              bathrooms,
+		LandArea:               landArea,
+		BuildingArea:           buildingArea,
+		Description:            description,
+		Images:                 images,
+		AgentName:              agentName,
+		AgentPhone:             agentPhone,
+		ScrapedAt:              time.Now(),
+		UpdatedAt:              time.Now(),
 	}
 
 	return listing, nil
 }
+
+// listingType returns the type and rent period of a listing with the given
+// price. A price with a rent period is rent on any site; on a rent site, a
+// price without one is rent per the site's default period, which is then
+// recorded on the price too.
+func (s *CollyScraper) listingType(price *model.Price) (string, string) {
+	if price.Period != model.PricePeriodSale {
+		return model.ListingTypeRent, price.Period
+	}
+	if s.config.ListingType != model.ListingTypeRent {
+		return model.ListingTypeSale, ""
+	}
+
+	price.Period = s.config.RentPeriod
+	if price.Period == "" {
+		price.Period = model.PricePeriodMonthly
+	}
+	return model.ListingTypeRent, price.Period
+}
+
+// normalizeLocation resolves a location with the embedded gazetteer. Misses
+// are common, so they are logged at debug level only and summed up per run
+// by the service.
+func (s *CollyScraper) normalizeLocation(location string) *model.Address {
+	addr := geo.Default().Normalize(location)
+	if addr == nil {
+		s.logger.Debug("location not covered by the gazetteer",
+			zap.String("site", s.config.Name),
+			zap.String("location", location))
+	}
+	return addr
+}
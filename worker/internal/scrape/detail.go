@@ -0,0 +1,154 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/pkg/retry"
+)
+
+// newDetailCollector creates a synchronous collector for detail pages with its
+// own rate limit, so enrichment does not eat into the search-page budget
+func newDetailCollector(cfg *config.SiteConfig) *colly.Collector {
+	c := colly.NewCollector(
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+		colly.AllowURLRevisit(),
+	)
+
+	rateLimit := cfg.DetailRateLimit
+	if rateLimit <= 0 {
+		rateLimit = cfg.RateLimit
+	}
+	if rateLimit <= 0 {
+		rateLimit = 1
+	}
+
+	c.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: 1,
+		Delay:       time.Second / time.Duration(rateLimit),
+	})
+
+	if cfg.Timeout > 0 {
+		c.SetRequestTimeout(time.Duration(cfg.Timeout) * time.Second)
+	}
+
+	return c
+}
+
+// ScrapeDetail visits the listing's detail page and merges the fields found
+// with the site's detail selectors into listing
+func (s *CollyScraper) ScrapeDetail(ctx context.Context, listing *model.Listing) error {
+	if listing.URL == "" {
+		return fmt.Errorf("missing detail URL")
+	}
+
+	sel := s.config.DetailSelectors
+	c := s.detailCollector.Clone()
+
+	var detail model.Listing
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		detail = extractDetail(e, sel)
+	})
+
+	err := retry.Do(ctx, retry.DefaultConfig(), func() error {
+		return c.Visit(listing.URL)
+	})
+	if err != nil {
+		return fmt.Errorf("visiting detail page: %w", err)
+	}
+
+	mergeDetail(listing, &detail)
+
+	s.logger.Debug("detail page scraped",
+		zap.String("site", s.config.Name),
+		zap.String("url", listing.URL))
+
+	return nil
+}
+
+// extractDetail reads the configured detail selectors from a detail page
+func extractDetail(e *colly.HTMLElement, sel config.DetailSelectorConfig) model.Listing {
+	var detail model.Listing
+
+	if sel.Description != "" {
+		detail.Description = CleanText(e.ChildText(sel.Description))
+	}
+	if sel.AgentName != "" {
+		detail.AgentName = CleanText(e.ChildText(sel.AgentName))
+	}
+	if sel.AgentPhone != "" {
+		detail.AgentPhone = CleanText(e.ChildText(sel.AgentPhone))
+	}
+	if sel.Bedrooms != "" {
+		detail.Bedrooms = ParseInt(e.ChildText(sel.Bedrooms))
+	}
+	if sel.Bathrooms != "" {
+		detail.Bathrooms = ParseInt(e.ChildText(sel.Bathrooms))
+	}
+	var landText, buildingText string
+	if sel.LandArea != "" {
+		landText = e.ChildText(sel.LandArea)
+	}
+	if sel.BuildingArea != "" {
+		buildingText = e.ChildText(sel.BuildingArea)
+	}
+	detail.LandArea, detail.BuildingArea = landAndBuildingArea(landText, buildingText)
+	if sel.Images != "" {
+		e.ForEach(sel.Images, func(_ int, img *colly.HTMLElement) {
+			src := img.Attr("src")
+			if src == "" {
+				src = img.Attr("data-src")
+			}
+			if src != "" {
+				detail.Images = append(detail.Images, e.Request.AbsoluteURL(src))
+			}
+		})
+	}
+
+	return detail
+}
+
+// mergeDetail copies every non-empty field of detail into listing. Detail
+// pages are the more complete source, so their values win over card values,
+// and images from both are kept without duplicates.
+func mergeDetail(listing, detail *model.Listing) {
+	if detail.Description != "" {
+		listing.Description = detail.Description
+	}
+	if detail.AgentName != "" {
+		listing.AgentName = detail.AgentName
+	}
+	if detail.AgentPhone != "" {
+		listing.AgentPhone = detail.AgentPhone
+	}
+	if detail.Bedrooms > 0 {
+		listing.Bedrooms = detail.Bedrooms
+	}
+	if detail.Bathrooms > 0 {
+		listing.Bathrooms = detail.Bathrooms
+	}
+	if detail.LandArea > 0 {
+		listing.LandArea = detail.LandArea
+	}
+	if detail.BuildingArea > 0 {
+		listing.BuildingArea = detail.BuildingArea
+	}
+
+	seen := make(map[string]bool, len(listing.Images))
+	for _, img := range listing.Images {
+		seen[img] = true
+	}
+	for _, img := range detail.Images {
+		if !seen[img] {
+			listing.Images = append(listing.Images, img)
+			seen[img] = true
+		}
+	}
+}
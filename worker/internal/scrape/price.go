@@ -0,0 +1,179 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+var (
+	// priceAmountRe matches a number with an optional magnitude word. The
+	// trailing group keeps units such as "m2" from being read as miliar.
+	priceAmountRe = regexp.MustCompile(`(\d+(?:[.,]\d+)*)\s*(triliun|miliar|milyar|juta|ribu|jt|rb|m|b|t|k)?(?:[^a-z0-9²]|$)`)
+
+	// Rent periods, optionally with a count ("/ 1 tahun")
+	pricePerMonthRe = regexp.MustCompile(`(?:/|\bper\b)\s*(?:1\s*)?(?:bulan|bln|bl|month|mo)\b|\bbulanan\b`)
+	pricePerYearRe  = regexp.MustCompile(`(?:/|\bper\b)\s*(?:1\s*)?(?:tahun|thn|th|year|yr)\b|\btahunan\b`)
+
+	priceNegotiableRe = regexp.MustCompile(`\bnego`)
+	priceFromRe       = regexp.MustCompile(`\b(?:mulai|mulai dari|start from|from)\b`)
+
+	// priceRangeSepRe matches what may stand between the bounds of a range
+	priceRangeSepRe = regexp.MustCompile(`^\s*(?:rp\.?)?\s*(?:-|–|—|~|s/d|sd|sampai|hingga|to)\s*(?:rp\.?)?\s*$`)
+)
+
+// priceMultipliers maps magnitude words to their value
+var priceMultipliers = map[string]float64{
+	"triliun": 1e12, "t": 1e12,
+	"miliar": 1e9, "milyar": 1e9, "m": 1e9, "b": 1e9,
+	"juta": 1e6, "jt": 1e6,
+	"ribu": 1e3, "rb": 1e3, "k": 1e3,
+}
+
+// ParsePriceInfo parses an Indonesian price string such as "Rp 1,5 M",
+// "Rp 850 Jt (Nego)", "Rp 2,3 Miliar - 2,8 Miliar" or "Rp 45 Jt/tahun".
+// Dots group thousands and a comma is the decimal separator, as is usual in
+// Indonesia; English-style numbers are recognised where unambiguous. A
+// string without a number, such as "Harga Nego", yields a zero amount.
+func ParsePriceInfo(s string) (model.Price, error) {
+	text := strings.ToLower(CleanText(strings.ReplaceAll(s, "\u00a0", " ")))
+	if text == "" {
+		return model.Price{}, fmt.Errorf("empty price string")
+	}
+
+	price := model.Price{
+		Period:     model.PricePeriodSale,
+		Negotiable: priceNegotiableRe.MatchString(text),
+	}
+
+	switch {
+	case pricePerMonthRe.MatchString(text):
+		price.Period = model.PricePeriodMonthly
+		text = pricePerMonthRe.ReplaceAllString(text, " ")
+	case pricePerYearRe.MatchString(text):
+		price.Period = model.PricePeriodYearly
+		text = pricePerYearRe.ReplaceAllString(text, " ")
+	}
+
+	matches := priceAmountRe.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		if price.Negotiable {
+			return price, nil
+		}
+		return model.Price{}, fmt.Errorf("no numeric value found in: %s", s)
+	}
+
+	first, err := parseAmount(text, matches[0])
+	if err != nil {
+		return model.Price{}, fmt.Errorf("parsing price %q: %w", s, err)
+	}
+
+	if len(matches) > 1 && priceRangeSepRe.MatchString(text[amountEnd(matches[0]):matches[1][0]]) {
+		second, err := parseAmount(text, matches[1])
+		if err != nil {
+			return model.Price{}, fmt.Errorf("parsing price %q: %w", s, err)
+		}
+
+		// "1,2 - 1,5 M" gives the unit once, after the upper bound
+		if first.unit == "" && second.unit != "" && first.value < 1000 {
+			first.value *= priceMultipliers[second.unit]
+		}
+
+		price.Min, price.Max = min(first.value, second.value), max(first.value, second.value)
+		price.Amount = price.Min
+		return price, nil
+	}
+
+	price.Amount = first.value
+	if priceFromRe.MatchString(text[:matches[0][0]]) {
+		price.Min = price.Amount
+	}
+	return price, nil
+}
+
+// ParsePrice extracts the numeric price from an Indonesian price string; see
+// ParsePriceInfo. A string without an amount is an error.
+func ParsePrice(s string) (float64, error) {
+	price, err := ParsePriceInfo(s)
+	if err != nil {
+		return 0, err
+	}
+	if price.Amount <= 0 {
+		return 0, fmt.Errorf("no numeric value found in: %s", s)
+	}
+	return price.Amount, nil
+}
+
+// amount is a number found in a price string with its magnitude applied
+type amount struct {
+	value float64
+	unit  string
+}
+
+// amountEnd returns where the number and magnitude word of a priceAmountRe
+// match end, before the boundary character the match consumed
+func amountEnd(m []int) int {
+	if m[5] >= 0 {
+		return m[5]
+	}
+	return m[3]
+}
+
+// parseAmount reads the number and magnitude word of a priceAmountRe match
+func parseAmount(text string, m []int) (amount, error) {
+	var unit string
+	if m[4] >= 0 {
+		unit = text[m[4]:m[5]]
+	}
+
+	n, err := parseNumber(text[m[2]:m[3]], unit != "")
+	if err != nil {
+		return amount{}, err
+	}
+	if unit != "" {
+		n *= priceMultipliers[unit]
+	}
+	return amount{value: n, unit: unit}, nil
+}
+
+// parseNumber parses a number with Indonesian or English separators.
+// withUnit tells that a magnitude word follows, which makes a single comma
+// a decimal separator ("1,250 M") rather than a thousands separator.
+func parseNumber(s string, withUnit bool) (float64, error) {
+	dots, commas := strings.Count(s, "."), strings.Count(s, ",")
+
+	switch {
+	case dots > 0 && commas > 0:
+		// The later separator is the decimal one
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case dots > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	case commas > 1:
+		s = strings.ReplaceAll(s, ",", "")
+	case dots == 1:
+		// "1.500" groups thousands, "1.5" is a decimal
+		if len(s)-strings.Index(s, ".")-1 == 3 {
+			s = strings.ReplaceAll(s, ".", "")
+		}
+	case commas == 1:
+		if !withUnit && len(s)-strings.Index(s, ",")-1 == 3 {
+			s = strings.ReplaceAll(s, ",", "")
+		} else {
+			s = strings.Replace(s, ",", ".", 1)
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing number: %w", err)
+	}
+	return n, nil
+}
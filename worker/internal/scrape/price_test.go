@@ -0,0 +1,112 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestParsePriceInfo(t *testing.T) {
+	const (
+		sale    = model.PricePeriodSale
+		monthly = model.PricePeriodMonthly
+		yearly  = model.PricePeriodYearly
+	)
+
+	tests := []struct {
+		input   string
+		want    model.Price
+		wantErr bool
+	}{
+		// Plain rupiah amounts
+		{input: "Rp 1.000.000.000", want: model.Price{Amount: 1e9, Period: sale}},
+		{input: "Rp 750.000.000", want: model.Price{Amount: 75e7, Period: sale}},
+		{input: "Rp. 2.150.000.000", want: model.Price{Amount: 215e7, Period: sale}},
+		{input: "Rp1.500", want: model.Price{Amount: 1500, Period: sale}},
+		{input: "IDR 850,000,000", want: model.Price{Amount: 85e7, Period: sale}},
+		{input: "Rp 850,000", want: model.Price{Amount: 850000, Period: sale}},
+		{input: "Rp 1.250.000,50", want: model.Price{Amount: 1250000.5, Period: sale}},
+
+		// Magnitude words
+		{input: "Rp 1,5 M", want: model.Price{Amount: 15e8, Period: sale}},
+		{input: "Rp 1,5M", want: model.Price{Amount: 15e8, Period: sale}},
+		{input: "Rp 1.5 M", want: model.Price{Amount: 15e8, Period: sale}},
+		{input: "Rp 1,25 Miliar", want: model.Price{Amount: 125e7, Period: sale}},
+		{input: "Rp 2,3 Miliar", want: model.Price{Amount: 23e8, Period: sale}},
+		{input: "Rp 3 Milyar", want: model.Price{Amount: 3e9, Period: sale}},
+		{input: "Rp 850 Jt", want: model.Price{Amount: 85e7, Period: sale}},
+		{input: "850 jt", want: model.Price{Amount: 85e7, Period: sale}},
+		{input: "Rp 500 Juta", want: model.Price{Amount: 5e8, Period: sale}},
+		{input: "Rp 1.500 Jt", want: model.Price{Amount: 15e8, Period: sale}},
+		{input: "Rp 1,250 M", want: model.Price{Amount: 125e7, Period: sale}},
+		{input: "Rp 1.250,5 Jt", want: model.Price{Amount: 12505e5, Period: sale}},
+		{input: "Rp 1,2 T", want: model.Price{Amount: 12e11, Period: sale}},
+		{input: "Rp 950 Rb", want: model.Price{Amount: 950000, Period: sale}},
+		{input: "Rp2M", want: model.Price{Amount: 2e9, Period: sale}},
+		{input: "IDR 1.5B", want: model.Price{Amount: 15e8, Period: sale}},
+
+		// Negotiable prices
+		{input: "Rp 1,5 M Nego", want: model.Price{Amount: 15e8, Period: sale, Negotiable: true}},
+		{input: "Rp 850 Jt (Nego)", want: model.Price{Amount: 85e7, Period: sale, Negotiable: true}},
+		{input: "Rp 2 M bisa nego", want: model.Price{Amount: 2e9, Period: sale, Negotiable: true}},
+		{input: "Harga Nego", want: model.Price{Period: sale, Negotiable: true}},
+		{input: "Negotiable", want: model.Price{Period: sale, Negotiable: true}},
+
+		// Ranges
+		{input: "Rp 1,2 M - 1,5 M", want: model.Price{Amount: 12e8, Period: sale, Min: 12e8, Max: 15e8}},
+		{input: "Rp 1,2 - 1,5 M", want: model.Price{Amount: 12e8, Period: sale, Min: 12e8, Max: 15e8}},
+		{input: "Rp 800 Jt - Rp 1,2 M", want: model.Price{Amount: 8e8, Period: sale, Min: 8e8, Max: 12e8}},
+		{input: "Rp 2,3 Miliar s/d 2,8 Miliar", want: model.Price{Amount: 23e8, Period: sale, Min: 23e8, Max: 28e8}},
+		{input: "Rp 500 Jt sampai 750 Jt", want: model.Price{Amount: 5e8, Period: sale, Min: 5e8, Max: 75e7}},
+		{input: "Rp 900.000.000 – 1,1 M", want: model.Price{Amount: 9e8, Period: sale, Min: 9e8, Max: 11e8}},
+		{input: "Mulai dari Rp 1,2 M", want: model.Price{Amount: 12e8, Period: sale, Min: 12e8}},
+		{input: "Mulai Rp 650 Jt", want: model.Price{Amount: 65e7, Period: sale, Min: 65e7}},
+
+		// Rents
+		{input: "Rp 3,5 Jt/bulan", want: model.Price{Amount: 35e5, Period: monthly}},
+		{input: "Rp 3,5 Jt / bulan", want: model.Price{Amount: 35e5, Period: monthly}},
+		{input: "Rp 15 Jt/bln", want: model.Price{Amount: 15e6, Period: monthly}},
+		{input: "Rp 4.500.000 per bulan", want: model.Price{Amount: 45e5, Period: monthly}},
+		{input: "Rp 800rb/bulan", want: model.Price{Amount: 8e5, Period: monthly}},
+		{input: "Rp 45 Jt/tahun", want: model.Price{Amount: 45e6, Period: yearly}},
+		{input: "Rp 25 Jt/thn", want: model.Price{Amount: 25e6, Period: yearly}},
+		{input: "Rp 120.000.000 / 1 tahun", want: model.Price{Amount: 12e7, Period: yearly}},
+		{input: "Rp 60 Jt per tahun (nego)", want: model.Price{Amount: 6e7, Period: yearly, Negotiable: true}},
+		{input: "Sewa tahunan Rp 75 Jt", want: model.Price{Amount: 75e6, Period: yearly}},
+		{input: "Rp 5 - 7 Jt/bulan", want: model.Price{Amount: 5e6, Period: monthly, Min: 5e6, Max: 7e6}},
+
+		// Not a price
+		{input: "", wantErr: true},
+		{input: "   ", wantErr: true},
+		{input: "Hubungi Agen", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePriceInfo(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	got, err := ParsePrice("Rp 1,5 M")
+	if err != nil || got != 15e8 {
+		t.Fatalf("expected 1.5e9, got %v (%v)", got, err)
+	}
+
+	if _, err := ParsePrice("Harga Nego"); err == nil {
+		t.Fatal("expected an error for a price without amount")
+	}
+}
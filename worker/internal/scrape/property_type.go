@@ -0,0 +1,210 @@
+package scrape
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// Weights of the signals a property type is derived from. A site label is
+// the most reliable; titles often mention other types ("Rumah hitung
+// tanah"), so they weigh least.
+const (
+	propertyLabelWeight = 0.9
+	propertyURLWeight   = 0.8
+	propertyTitleWeight = 0.6
+)
+
+// Property type rule sources
+const (
+	propertySourceURL   = "url"
+	propertySourceLabel = "label"
+	propertySourceTitle = "title"
+)
+
+var (
+	// propertyKeywordRe matches property type keywords, one group per type.
+	// Phrases are listed before the words they start with, so that "rumah
+	// toko" is a shophouse rather than a house.
+	propertyKeywordRe = regexp.MustCompile(`\b(?:` +
+		`(rumah toko|rumah kantor|ruko|rukan|shophouse)|` +
+		`(apartemen|apartment|apart|apt|kondominium|condominium|condo)|` +
+		`(kavling|kaveling|tanah|lahan|land)|` +
+		`(gudang|warehouse|pabrik|factory)|` +
+		`(rumah|house|villa|townhouse)` +
+		`)\b`)
+
+	// propertyKeywordTypes are the property types of propertyKeywordRe's groups
+	propertyKeywordTypes = []string{
+		model.PropertyTypeShophouse,
+		model.PropertyTypeApartment,
+		model.PropertyTypeLand,
+		model.PropertyTypeWarehouse,
+		model.PropertyTypeHouse,
+	}
+
+	// propertyNoiseRe matches phrases that contain a keyword without naming
+	// the property type: area labels and place names
+	propertyNoiseRe = regexp.MustCompile(`\b(?:luas tanah|luas bangunan|tanah (?:abang|kusir|tinggi|merah|sareal|baru)|kebon kacang)\b`)
+
+	// propertyLandValueRe matches titles of houses sold for their land value
+	propertyLandValueRe = regexp.MustCompile(`\b(?:hitung tanah|harga tanah)\b`)
+)
+
+// propertyTypeRule is a compiled config.PropertyTypeRule
+type propertyTypeRule struct {
+	re           *regexp.Regexp
+	source       string
+	propertyType string
+}
+
+// PropertyClassifier tells the property type of a listing from its URLs, the
+// label the site shows and its title
+type PropertyClassifier struct {
+	rules []propertyTypeRule
+}
+
+// PropertySignals are what a listing's property type is derived from. URLs
+// holds the listing URL and the search page it was found on.
+type PropertySignals struct {
+	URLs  []string
+	Label string
+	Title string
+}
+
+// NewPropertyClassifier creates a classifier applying the override rules
+// before the built-in classification
+func NewPropertyClassifier(rules []config.PropertyTypeRule) (*PropertyClassifier, error) {
+	c := &PropertyClassifier{rules: make([]propertyTypeRule, 0, len(rules))}
+	for i, rule := range rules {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling property type rule %d: %w", i, err)
+		}
+		c.rules = append(c.rules, propertyTypeRule{re: re, source: rule.Source, propertyType: rule.Type})
+	}
+	return c, nil
+}
+
+// Classify returns the property type of a listing and the confidence in it.
+// A matching override rule gives full confidence. Otherwise every signal
+// votes with its weight; the confidence combines the weights of the signals
+// that agree with the winner and is scaled down by those that disagree. A
+// listing without any signal gets an empty type.
+func (c *PropertyClassifier) Classify(sig PropertySignals) (string, float64) {
+	if t, ok := c.override(sig); ok {
+		return t, 1
+	}
+
+	// missing holds the chance that all signals for a type are wrong
+	missing := make(map[string]float64)
+	vote := func(t string, weight float64) {
+		if t == "" {
+			return
+		}
+		if _, ok := missing[t]; !ok {
+			missing[t] = 1
+		}
+		missing[t] *= 1 - weight
+	}
+
+	vote(keywordType(sig.Label), propertyLabelWeight)
+	for _, u := range sig.URLs {
+		vote(urlType(u), propertyURLWeight)
+	}
+	vote(titleType(sig.Title), propertyTitleWeight)
+
+	var best string
+	var bestScore, total float64
+	for _, t := range model.PropertyTypes {
+		m, ok := missing[t]
+		if !ok {
+			continue
+		}
+		score := 1 - m
+		total += score
+		if score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+
+	confidence := bestScore * bestScore / total
+	return best, math.Round(confidence*100) / 100
+}
+
+// override returns the type of the first rule matching its source
+func (c *PropertyClassifier) override(sig PropertySignals) (string, bool) {
+	for _, rule := range c.rules {
+		var texts []string
+		switch rule.source {
+		case propertySourceURL:
+			texts = sig.URLs
+		case propertySourceLabel:
+			texts = []string{sig.Label}
+		case propertySourceTitle:
+			texts = []string{sig.Title}
+		default:
+			texts = append([]string{sig.Label, sig.Title}, sig.URLs...)
+		}
+
+		for _, text := range texts {
+			if text != "" && rule.re.MatchString(text) {
+				return rule.propertyType, true
+			}
+		}
+	}
+	return "", false
+}
+
+// keywordType returns the type named by the first property keyword in s
+func keywordType(s string) string {
+	text := strings.ToLower(CleanText(s))
+	text = propertyNoiseRe.ReplaceAllString(text, " ")
+
+	m := propertyKeywordRe.FindStringSubmatchIndex(text)
+	if m == nil {
+		return ""
+	}
+	for i, t := range propertyKeywordTypes {
+		if m[2+2*i] >= 0 {
+			return t
+		}
+	}
+	return ""
+}
+
+// titleType returns the type a title names. A house sold for its land value
+// ("Rumah hitung tanah") counts as land.
+func titleType(title string) string {
+	if propertyLandValueRe.MatchString(strings.ToLower(title)) {
+		return model.PropertyTypeLand
+	}
+	return keywordType(title)
+}
+
+// urlType returns the type named by a path segment of u that consists of a
+// property keyword only, as in "/jual/jakarta-selatan/rumah/". Slugs such as
+// "dijual-rumah-murah" repeat the title and are left to it.
+func urlType(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		words := strings.ReplaceAll(segment, "-", " ")
+		m := propertyKeywordRe.FindStringIndex(strings.ToLower(words))
+		if m != nil && m[0] == 0 && m[1] == len(words) {
+			return keywordType(words)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestPropertyClassifier(t *testing.T) {
+	classifier, err := NewPropertyClassifier([]config.PropertyTypeRule{
+		{Pattern: `/gudang-dan-pabrik/`, Source: "url", Type: model.PropertyTypeWarehouse},
+		{Pattern: `\bkios\b`, Source: "title", Type: model.PropertyTypeShophouse},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const searchURL = "https://www.rumah123.com/jual/jakarta-selatan/rumah/"
+
+	tests := []struct {
+		name           string
+		signals        PropertySignals
+		wantType       string
+		wantConfidence float64
+	}{
+		// Titles alone
+		{name: "house title", signals: PropertySignals{Title: "Dijual Rumah Minimalis Siap Huni"}, wantType: "rumah", wantConfidence: 0.6},
+		{name: "land title", signals: PropertySignals{Title: "Dijual Tanah Kavling 200 m²"}, wantType: "tanah", wantConfidence: 0.6},
+		{name: "shophouse title", signals: PropertySignals{Title: "Ruko 3 lantai pinggir jalan"}, wantType: "ruko", wantConfidence: 0.6},
+		{name: "rumah toko", signals: PropertySignals{Title: "Rumah Toko strategis"}, wantType: "ruko", wantConfidence: 0.6},
+		{name: "apartment title", signals: PropertySignals{Title: "Apartemen 2BR Kalibata City"}, wantType: "apartemen", wantConfidence: 0.6},
+		{name: "warehouse title", signals: PropertySignals{Title: "Gudang Cikarang 1.000 m2"}, wantType: "gudang", wantConfidence: 0.6},
+		{name: "first keyword wins", signals: PropertySignals{Title: "Tanah bonus rumah tua"}, wantType: "tanah", wantConfidence: 0.6},
+		{name: "land value", signals: PropertySignals{Title: "Rumah Tua Hitung Tanah"}, wantType: "tanah", wantConfidence: 0.6},
+		{name: "area label", signals: PropertySignals{Title: "Luas Tanah 300, Rumah 2 Lantai"}, wantType: "rumah", wantConfidence: 0.6},
+		{name: "place name", signals: PropertySignals{Title: "Dijual Cepat di Tanah Abang"}, wantType: "", wantConfidence: 0},
+
+		// Combined signals
+		{name: "url and title agree", signals: PropertySignals{URLs: []string{searchURL}, Title: "Rumah Pondok Indah"}, wantType: "rumah", wantConfidence: 0.92},
+		{name: "label", signals: PropertySignals{Label: "Apartemen", Title: "Unit 2BR view kota"}, wantType: "apartemen", wantConfidence: 0.9},
+		{name: "url beats title", signals: PropertySignals{URLs: []string{"https://example.com/jual/tanah/bekasi/"}, Title: "Rumah Hitung Bangunan"}, wantType: "tanah", wantConfidence: 0.46},
+		{name: "slug ignored", signals: PropertySignals{URLs: []string{"https://example.com/properti/dijual-tanah-murah-123"}}, wantType: "", wantConfidence: 0},
+		{name: "no signals", signals: PropertySignals{Title: "Hunian nyaman di Jakarta"}, wantType: "", wantConfidence: 0},
+
+		// Override rules
+		{name: "url rule", signals: PropertySignals{URLs: []string{"https://example.com/jual/gudang-dan-pabrik/"}, Title: "Rumah"}, wantType: "gudang", wantConfidence: 1},
+		{name: "title rule", signals: PropertySignals{Title: "Kios di pasar"}, wantType: "ruko", wantConfidence: 1},
+		{name: "rule source", signals: PropertySignals{Label: "Kios", Title: "Rumah"}, wantType: "rumah", wantConfidence: 0.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotConfidence := classifier.Classify(tt.signals)
+			if gotType != tt.wantType || gotConfidence != tt.wantConfidence {
+				t.Fatalf("expected %q (%v), got %q (%v)", tt.wantType, tt.wantConfidence, gotType, gotConfidence)
+			}
+		})
+	}
+}
+
+func TestNewPropertyClassifierInvalidRule(t *testing.T) {
+	if _, err := NewPropertyClassifier([]config.PropertyTypeRule{{Pattern: "(", Type: model.PropertyTypeHouse}}); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
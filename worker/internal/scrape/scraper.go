@@ -11,3 +11,19 @@ type Scraper interface {
 	// Scrape fetches and parses listings from the given URL
 	Scrape(ctx context.Context, url string) (*model.ScrapeResult, error)
 }
+
+// StreamScraper is implemented by scrapers that hand over listings while a
+// page is still being parsed instead of collecting them in the result
+type StreamScraper interface {
+	// ScrapeStream fetches url and passes every parsed listing to onListing as
+	// soon as it is extracted. An error returned by onListing stops the scrape
+	// and is returned wrapped. onProgress may be nil. The returned result holds
+	// counts and pagination but no listings.
+	ScrapeStream(ctx context.Context, url string, onListing func(*model.Listing) error, onProgress func(model.ScrapeProgress)) (*model.ScrapeResult, error)
+}
+
+// DetailScraper is implemented by scrapers that can enrich a listing from its detail page
+type DetailScraper interface {
+	// ScrapeDetail visits listing.URL and merges the detail-page fields into listing
+	ScrapeDetail(ctx context.Context, listing *model.Listing) error
+}
@@ -1,7 +1,10 @@
 package site
 
 import (
+	"context"
+
 	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
 	"github.com/Alwanly/Houses-Prices/worker/internal/scrape"
 	"go.uber.org/zap"
 )
@@ -21,3 +24,8 @@ func NewBaseScraper(cfg *config.SiteConfig, logger *zap.Logger) *BaseScraper {
 		Logger: logger,
 	}
 }
+
+// ScrapeDetail enriches a listing from its detail page using the site's detail selectors
+func (b *BaseScraper) ScrapeDetail(ctx context.Context, listing *model.Listing) error {
+	return b.Colly.ScrapeDetail(ctx, listing)
+}
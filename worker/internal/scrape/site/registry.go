@@ -0,0 +1,119 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/scrape"
+)
+
+// GenericScraper is the registry name of the config-driven CollyScraper used
+// by every site that has no dedicated implementation
+const GenericScraper = "generic"
+
+// Factory builds a scraper for a configured site
+type Factory func(cfg *config.SiteConfig, logger *zap.Logger) scrape.Scraper
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		GenericScraper: func(cfg *config.SiteConfig, logger *zap.Logger) scrape.Scraper {
+			return scrape.NewCollyScraper(cfg, logger)
+		},
+	}
+)
+
+// Register makes a scraper factory available under name. It is meant to be
+// called from the init function of a site implementation and panics if name
+// is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("site: Register factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("site: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the sorted names of all registered scrapers
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the scraper for a site. An explicit scraper name in the config
+// wins; otherwise a scraper registered under the site name is used, falling
+// back to the generic scraper with a warning, since a misspelt site name
+// silently losing its dedicated scraper is hard to spot otherwise.
+func New(cfg *config.SiteConfig, logger *zap.Logger) (scrape.Scraper, error) {
+	registryMu.RLock()
+	name := scraperName(cfg)
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown scraper %q for site %s", name, cfg.Name)
+	}
+
+	if cfg.Scraper == "" && name == GenericScraper {
+		logger.Warn("no scraper registered for site, using the generic scraper; set scraper: generic to silence this",
+			zap.String("site", cfg.Name))
+	} else {
+		logger.Info("scraper resolved",
+			zap.String("site", cfg.Name),
+			zap.String("scraper", name))
+	}
+
+	return factory(cfg, logger), nil
+}
+
+// ValidateConfig checks that every site resolves to a registered scraper and
+// that site names are unique
+func ValidateConfig(sites []config.SiteConfig) error {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var errs []error
+	seen := make(map[string]bool, len(sites))
+	for i := range sites {
+		cfg := &sites[i]
+		if seen[cfg.Name] {
+			errs = append(errs, fmt.Errorf("sites[%d]: duplicate site name %q", i, cfg.Name))
+		}
+		seen[cfg.Name] = true
+
+		if name := scraperName(cfg); registry[name] == nil {
+			errs = append(errs, fmt.Errorf("sites[%d]: unknown scraper %q for site %q", i, name, cfg.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// scraperName resolves the registry name for a site; callers must hold the
+// registry read lock
+func scraperName(cfg *config.SiteConfig) string {
+	if cfg.Scraper != "" {
+		return cfg.Scraper
+	}
+	if _, ok := registry[cfg.Name]; ok {
+		return cfg.Name
+	}
+	return GenericScraper
+}
@@ -0,0 +1,143 @@
+package site
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/scrape"
+)
+
+// stubScraper records the site it was built for
+type stubScraper struct {
+	site string
+}
+
+func (s *stubScraper) Scrape(context.Context, string) (*model.ScrapeResult, error) {
+	return &model.ScrapeResult{}, nil
+}
+
+func stubFactory(cfg *config.SiteConfig, _ *zap.Logger) scrape.Scraper {
+	return &stubScraper{site: cfg.Name}
+}
+
+func TestRegisterPanicsOnDuplicate(t *testing.T) {
+	Register("test-register-dup", stubFactory)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic on duplicate registration")
+		}
+	}()
+	Register("test-register-dup", stubFactory)
+}
+
+func TestRegisterPanicsOnNilFactory(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic on a nil factory")
+		}
+	}()
+	Register("test-register-nil", nil)
+}
+
+func TestNew(t *testing.T) {
+	Register("test-new", stubFactory)
+
+	tests := []struct {
+		name     string
+		cfg      config.SiteConfig
+		wantStub bool
+		wantWarn bool
+		wantErr  bool
+	}{
+		{name: "registered under site name", cfg: config.SiteConfig{Name: "test-new"}, wantStub: true},
+		{name: "explicit scraper", cfg: config.SiteConfig{Name: "other", Scraper: "test-new"}, wantStub: true},
+		{name: "explicit generic", cfg: config.SiteConfig{Name: "other", Scraper: GenericScraper}},
+		{name: "implicit generic", cfg: config.SiteConfig{Name: "other"}, wantWarn: true},
+		{name: "unknown scraper", cfg: config.SiteConfig{Name: "other", Scraper: "missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.RateLimit = 1
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			s, err := New(&cfg, zap.New(core))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if _, isStub := s.(*stubScraper); isStub != tt.wantStub {
+				t.Errorf("expected stub scraper %v, got %T", tt.wantStub, s)
+			}
+			if warned := logs.Len() > 0; warned != tt.wantWarn {
+				t.Errorf("expected warning %v, got %v", tt.wantWarn, logs.All())
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		sites   []config.SiteConfig
+		wantErr []string
+	}{
+		{
+			name:  "valid",
+			sites: []config.SiteConfig{{Name: "a"}, {Name: "b", Scraper: GenericScraper}},
+		},
+		{
+			name:    "duplicate site name",
+			sites:   []config.SiteConfig{{Name: "a"}, {Name: "a"}},
+			wantErr: []string{`sites[1]: duplicate site name "a"`},
+		},
+		{
+			name:    "unknown scraper",
+			sites:   []config.SiteConfig{{Name: "a", Scraper: "missing"}},
+			wantErr: []string{`sites[0]: unknown scraper "missing"`},
+		},
+		{
+			name:  "both",
+			sites: []config.SiteConfig{{Name: "a"}, {Name: "a", Scraper: "missing"}},
+			wantErr: []string{
+				`sites[1]: duplicate site name "a"`,
+				`sites[1]: unknown scraper "missing"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.sites)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected %q in %v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -8,8 +8,15 @@ import (
 
 	"github.com/Alwanly/Houses-Prices/worker/internal/config"
 	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/scrape"
 )
 
+func init() {
+	Register("rumah123", func(cfg *config.SiteConfig, logger *zap.Logger) scrape.Scraper {
+		return NewRumah123Scraper(cfg, logger)
+	})
+}
+
 // Rumah123Scraper implements scraping for rumah123.com
 type Rumah123Scraper struct {
 	*BaseScraper
@@ -33,9 +40,30 @@ func (s *Rumah123Scraper) Scrape(ctx context.Context, url string) (*model.Scrape
 		return nil, fmt.Errorf("scraping rumah123: %w", err)
 	}
 
-	// Site-specific post-processing can be added here
-	// For example: CAPTCHA detection, additional data enrichment, etc.
+	return s.checkResult(url, result)
+}
+
+// ScrapeStream implements the StreamScraper interface for rumah123.com
+func (s *Rumah123Scraper) ScrapeStream(
+	ctx context.Context,
+	url string,
+	onListing func(*model.Listing) error,
+	onProgress func(model.ScrapeProgress),
+) (*model.ScrapeResult, error) {
+	s.Logger.Info("starting rumah123 stream scrape",
+		zap.String("url", url))
+
+	result, err := s.Colly.ScrapeStream(ctx, url, onListing, onProgress)
+	if err != nil {
+		return nil, fmt.Errorf("scraping rumah123: %w", err)
+	}
+
+	return s.checkResult(url, result)
+}
 
+// checkResult applies site-specific post-processing to a scraped page.
+// For example: CAPTCHA detection, additional data enrichment, etc.
+func (s *Rumah123Scraper) checkResult(url string, result *model.ScrapeResult) (*model.ScrapeResult, error) {
 	// Check for CAPTCHA indicators
 	if s.detectCaptcha(result) {
 		s.Logger.Warn("CAPTCHA detected on rumah123",
@@ -44,7 +72,7 @@ func (s *Rumah123Scraper) Scrape(ctx context.Context, url string) (*model.Scrape
 	}
 
 	// Validate results
-	if len(result.Listings) == 0 {
+	if result.TotalScraped == 0 {
 		s.Logger.Warn("no listings found",
 			zap.String("url", url))
 	}
@@ -54,15 +82,7 @@ func (s *Rumah123Scraper) Scrape(ctx context.Context, url string) (*model.Scrape
 
 // detectCaptcha checks if CAPTCHA is present in results
 func (s *Rumah123Scraper) detectCaptcha(result *model.ScrapeResult) bool {
-	// CAPTCHA usually results in zero listings and specific error patterns
-	if len(result.Listings) == 0 && len(result.Errors) > 0 {
-		for _, errMsg := range result.Errors {
-			// Check for common CAPTCHA-related errors
-			if len(errMsg) > 0 {
-				// This is a simple check - enhance based on actual site behavior
-				return result.TotalScraped == 0
-			}
-		}
-	}
-	return false
+	// CAPTCHA usually results in zero listings and specific error patterns.
+	// This is a simple check - enhance based on actual site behavior
+	return result.TotalScraped == 0 && result.ErrorCount > 0
 }
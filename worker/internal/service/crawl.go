@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
+)
+
+// defaultStreamBuffer is the number of scraped listings that may wait for
+// saving before the scraper is blocked
+const defaultStreamBuffer = 100
+
+// defaultSaveBatchSize is the number of listings written per bulk save
+const defaultSaveBatchSize = 50
+
+// saveFlushInterval bounds how long a partial batch waits for more listings
+// before it is written anyway
+const saveFlushInterval = time.Second
+
+// errBudgetSpent stops a page scrape once the listing budget is used up
+var errBudgetSpent = errors.New("listing budget spent")
+
+// StreamScraper is implemented by scrapers that hand over listings while a
+// page is still being parsed
+type StreamScraper interface {
+	ScrapeStream(ctx context.Context, url string, onListing func(*model.Listing) error, onProgress func(model.ScrapeProgress)) (*model.ScrapeResult, error)
+}
+
+// crawlLimits bounds a single paginated crawl; zero values mean unlimited
+type crawlLimits struct {
+	maxPages      int
+	maxListings   int
+	streamBuffer  int
+	saveBatchSize int
+}
+
+func (s *ScraperService) crawlLimits(siteName string) crawlLimits {
+	limits := crawlLimits{
+		streamBuffer:  defaultStreamBuffer,
+		saveBatchSize: defaultSaveBatchSize,
+	}
+
+	cfg, ok := s.sites[siteName]
+	if !ok {
+		return limits
+	}
+
+	limits.maxPages = cfg.MaxPages
+	limits.maxListings = cfg.MaxListings
+	if cfg.StreamBuffer > 0 {
+		limits.streamBuffer = cfg.StreamBuffer
+	}
+	if cfg.SaveBatchSize > 0 {
+		limits.saveBatchSize = cfg.SaveBatchSize
+	}
+	return limits
+}
+
+// pageItem is a scraped listing on its way to the saver
+type pageItem struct {
+	page    int // index into job.Pages
+	listing *model.Listing
+}
+
+// crawlState is the job shared between the page producer and the saver
+type crawlState struct {
+	mu  sync.Mutex
+	job *model.JobStatus
+
+	// complete is set by the producer when pagination ran to its natural end
+	complete bool
+
+	// unsaved counts the listings the saver failed to write or had fenced
+	// off; they keep an older run_id although this run saw them
+	unsaved int
+
+	// unresolved counts the listings whose location resolved to no address;
+	// like complete, it is written by the producer only
+	unresolved int
+}
+
+// crawl scrapes startURL and every following result page. Listings are
+// handed to a saver goroutine through a bounded buffer while pages are still
+// being parsed, so a full buffer slows the scraper down instead of growing
+// memory, and everything scraped before a failure is still saved. Only a
+// failure on the first page is returned as an error; later failures end the
+// crawl with the pages collected so far. complete reports whether the last
+// result page was reached and every listing seen was saved, i.e. every
+// listing still on the site carries this run's ID.
+func (s *ScraperService) crawl(ctx context.Context, scraper Scraper, job *model.JobStatus, startURL string) (complete bool, err error) {
+	limits := s.crawlLimits(job.SiteName)
+	state := &crawlState{job: job}
+	items := make(chan pageItem, limits.streamBuffer)
+
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		s.saveListings(ctx, scraper, state, items, limits.saveBatchSize)
+	}()
+
+	err = s.paginate(ctx, scraper, state, items, startURL, limits)
+
+	close(items)
+	<-saved
+
+	if state.unresolved > 0 {
+		s.logger.Info("locations not covered by the gazetteer",
+			zap.String("site", job.SiteName),
+			zap.String("job_id", job.JobID),
+			zap.Int("listings", state.unresolved))
+	}
+	if state.complete && state.unsaved > 0 {
+		s.logger.Warn("crawl not counted as complete, some listings were not saved",
+			zap.String("site", job.SiteName),
+			zap.String("job_id", job.JobID),
+			zap.Int("unsaved", state.unsaved))
+	}
+	return state.complete && state.unsaved == 0 && err == nil, err
+}
+
+// paginate produces listings page by page until pagination ends, the budget
+// is spent, a loop is detected or a page fails
+func (s *ScraperService) paginate(
+	ctx context.Context,
+	scraper Scraper,
+	state *crawlState,
+	items chan<- pageItem,
+	startURL string,
+	limits crawlLimits,
+) error {
+	siteName := state.job.SiteName
+	visited := make(map[string]bool)
+	lastSignature := ""
+	total := 0
+
+	pageURL := startURL
+	for page := 1; pageURL != ""; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if limits.maxPages > 0 && page > limits.maxPages {
+			state.setMessage(fmt.Sprintf("max pages reached (%d)", limits.maxPages))
+			return nil
+		}
+
+		if limits.maxListings > 0 && total >= limits.maxListings {
+			state.setMessage(fmt.Sprintf("max listings reached (%d)", limits.maxListings))
+			return nil
+		}
+
+		key := normalizePageURL(pageURL)
+		if visited[key] {
+			s.logPaginationLoop(state, page, pageURL)
+			return nil
+		}
+		visited[key] = true
+
+		index := state.addPage(model.PageStats{Page: page, URL: pageURL})
+		pageStart := time.Now()
+		urls := make([]string, 0)
+
+		emit := func(listing *model.Listing) error {
+			if limits.maxListings > 0 && total >= limits.maxListings {
+				return errBudgetSpent
+			}
+			total++
+			urls = append(urls, listing.URL)
+			listing.SiteName = siteName
+			listing.RunID = state.job.JobID
+			listing.FenceToken = state.job.FenceToken
+			if listing.Address == nil && listing.Location != "" {
+				state.unresolved++
+			}
+
+			select {
+			case items <- pageItem{page: index, listing: listing}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		result, err := s.scrapePage(ctx, scraper, pageURL, emit)
+		budgetSpent := errors.Is(err, errBudgetSpent)
+
+		state.mu.Lock()
+		stats := &state.job.Pages[index]
+		stats.Scraped = len(urls)
+		stats.Duration = time.Since(pageStart).Seconds()
+		state.job.Scraped += len(urls)
+		if result != nil {
+			stats.Errors += result.ErrorCount
+			state.job.Errors += result.ErrorCount
+		}
+		state.mu.Unlock()
+
+		s.logger.Info("page scraped",
+			zap.String("site", siteName),
+			zap.Int("page", page),
+			zap.Int("scraped", len(urls)))
+		s.saveRun(ctx, state.snapshot())
+
+		if budgetSpent {
+			state.setMessage(fmt.Sprintf("max listings reached (%d)", limits.maxListings))
+			return nil
+		}
+
+		if err != nil {
+			if page == 1 || ctx.Err() != nil {
+				return err
+			}
+			s.logger.Warn("page scrape failed, stopping crawl",
+				zap.String("site", siteName),
+				zap.Int("page", page),
+				zap.String("url", pageURL),
+				zap.Error(err))
+			state.mu.Lock()
+			state.job.Errors++
+			state.job.Message = fmt.Sprintf("stopped at page %d: %v", page, err)
+			state.mu.Unlock()
+			return nil
+		}
+
+		// Some portals answer out-of-range page numbers with the last page
+		// again, so identical content marks the natural end of pagination.
+		// Its listings are already queued and are simply saved a second time.
+		signature := strings.Join(urls, "\n")
+		if signature != "" && signature == lastSignature {
+			s.logger.Info("last page repeated, pagination ended",
+				zap.String("site", siteName),
+				zap.Int("page", page),
+				zap.String("url", pageURL))
+			state.complete = true
+			return nil
+		}
+		lastSignature = signature
+
+		if !result.HasNextPage {
+			state.complete = true
+			return nil
+		}
+		pageURL = result.NextPageURL
+	}
+
+	return nil
+}
+
+// scrapePage streams one page through emit, falling back to a buffered
+// Scrape for scrapers without streaming support
+func (s *ScraperService) scrapePage(
+	ctx context.Context,
+	scraper Scraper,
+	pageURL string,
+	emit func(*model.Listing) error,
+) (*model.ScrapeResult, error) {
+	if streamer, ok := scraper.(StreamScraper); ok {
+		return streamer.ScrapeStream(ctx, pageURL, emit, s.logProgress)
+	}
+
+	result, err := scraper.Scrape(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, listing := range result.Listings {
+		if err := emit(listing); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// saveListings enriches listings and saves them in batches until items is
+// closed. A partial batch is flushed after saveFlushInterval so a slow page
+// does not hold scraped listings in memory. Listings already buffered when
+// ctx is cancelled are still saved, without their detail pass, so partial
+// progress survives an aborted crawl.
+func (s *ScraperService) saveListings(
+	ctx context.Context,
+	scraper Scraper,
+	state *crawlState,
+	items <-chan pageItem,
+	batchSize int,
+) {
+	saveCtx := context.WithoutCancel(ctx)
+	batch := make([]pageItem, 0, batchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if ctx.Err() == nil {
+			s.enrichBatch(ctx, scraper, state, batch)
+		}
+		s.saveBatch(saveCtx, state, batch)
+		batch = batch[:0]
+	}
+
+	ticker := time.NewTicker(saveFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, item)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// saveBatch writes a batch with one bulk save and books the outcome of every
+// listing on its page
+func (s *ScraperService) saveBatch(ctx context.Context, state *crawlState, batch []pageItem) {
+	listings := make([]*model.Listing, len(batch))
+	for i, item := range batch {
+		listings[i] = item.listing
+	}
+
+	result, err := s.repository.SaveMany(ctx, listings)
+	if err != nil {
+		s.logger.Error("failed to save listings", zap.Int("count", len(batch)), zap.Error(err))
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	job := state.job
+	for i, item := range batch {
+		stats := &job.Pages[item.page]
+
+		// A later copy of the listing in the batch was saved in its place
+		if result != nil && result.Outcomes[i] == storage.SaveDuplicate {
+			continue
+		}
+
+		if result != nil && result.Outcomes[i] == storage.SaveStale {
+			stats.Errors++
+			job.Errors++
+			job.StaleWrites++
+			state.unsaved++
+			continue
+		}
+
+		if result == nil || result.Outcomes[i] == storage.SaveFailed {
+			if result != nil {
+				s.logger.Error("failed to save listing",
+					zap.String("url", item.listing.URL),
+					zap.Error(result.Errors[i]))
+			}
+			stats.Errors++
+			job.Errors++
+			state.unsaved++
+			continue
+		}
+
+		stats.Saved++
+		job.Saved++
+		switch result.Outcomes[i] {
+		case storage.SaveInserted:
+			job.Inserted++
+		case storage.SaveUpdated:
+			job.Updated++
+		case storage.SaveUnchanged:
+			job.Unchanged++
+		}
+	}
+
+	if result == nil {
+		return
+	}
+	job.PriceChanged += result.PriceChanged
+	if result.Stale > 0 {
+		s.logger.Warn("listing writes rejected, lock is held by a newer run",
+			zap.String("site", job.SiteName),
+			zap.String("job_id", job.JobID),
+			zap.Int64("fence_token", job.FenceToken),
+			zap.Int("rejected", result.Stale))
+	}
+}
+
+func (s *ScraperService) logProgress(p model.ScrapeProgress) {
+	s.logger.Debug("scrape progress",
+		zap.String("site", p.SiteName),
+		zap.String("url", p.URL),
+		zap.Int("scraped", p.Scraped),
+		zap.Int("errors", p.Errors),
+		zap.Bool("done", p.Done))
+}
+
+func (s *ScraperService) logPaginationLoop(state *crawlState, page int, pageURL string) {
+	s.logger.Warn("pagination loop detected",
+		zap.String("site", state.job.SiteName),
+		zap.Int("page", page),
+		zap.String("url", pageURL))
+	state.setMessage(fmt.Sprintf("pagination loop detected at page %d", page))
+}
+
+// addPage appends page statistics and returns their index
+func (c *crawlState) addPage(stats model.PageStats) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.job.Pages = append(c.job.Pages, stats)
+	return len(c.job.Pages) - 1
+}
+
+// snapshot copies the job so it can be stored while the saver keeps updating it
+func (c *crawlState) snapshot() *model.JobStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job := *c.job
+	job.Pages = slices.Clone(c.job.Pages)
+	return &job
+}
+
+func (c *crawlState) setMessage(msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.job.Message = msg
+}
+
+// normalizePageURL reduces a page URL to a comparable key so that trivially
+// different spellings of the same page are recognised as already visited
+func normalizePageURL(raw string) string {
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Fragment = ""
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
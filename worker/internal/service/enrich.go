@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// DetailScraper is implemented by scrapers that can enrich a listing from its detail page
+type DetailScraper interface {
+	ScrapeDetail(ctx context.Context, listing *model.Listing) error
+}
+
+// Detail enrichment modes
+const (
+	detailModeOff     = "off"
+	detailModeAll     = "all"
+	detailModeNew     = "new"
+	detailModeChanged = "changed"
+)
+
+// detailMode resolves the enrichment mode of a site. Sites without detail
+// selectors are never enriched.
+func (s *ScraperService) detailMode(siteName string) string {
+	cfg, ok := s.sites[siteName]
+	if !ok || cfg.DetailSelectors == (config.DetailSelectorConfig{}) {
+		return detailModeOff
+	}
+	if cfg.DetailMode == "" {
+		return detailModeAll
+	}
+	return cfg.DetailMode
+}
+
+// enrichBatch runs the detail-page pass over a batch according to the site's
+// detail mode. The stored copies of the batch's listings are loaded with one
+// query. Listings that are skipped or fail enrichment keep the detail fields
+// already stored, so a card-only re-scrape never blanks them out.
+func (s *ScraperService) enrichBatch(ctx context.Context, scraper Scraper, state *crawlState, batch []pageItem) {
+	mode := s.detailMode(state.job.SiteName)
+	if mode == detailModeOff {
+		return
+	}
+
+	detailScraper, ok := scraper.(DetailScraper)
+	if !ok {
+		return
+	}
+
+	urls := make([]string, len(batch))
+	for i, item := range batch {
+		urls[i] = item.listing.URL
+	}
+	existing, err := s.repository.FindByURLs(ctx, urls)
+	if err != nil {
+		s.logger.Warn("failed to look up listings for enrichment",
+			zap.Int("count", len(urls)),
+			zap.Error(err))
+		existing = nil
+	}
+
+	for _, item := range batch {
+		if ctx.Err() != nil {
+			return
+		}
+		if s.enrich(ctx, detailScraper, mode, item.listing, existing[item.listing.URL]) {
+			state.mu.Lock()
+			state.job.Pages[item.page].Enriched++
+			state.mu.Unlock()
+		}
+	}
+}
+
+// enrich runs the detail-page pass for one listing and reports whether the
+// detail page was visited
+func (s *ScraperService) enrich(
+	ctx context.Context,
+	detailScraper DetailScraper,
+	mode string,
+	listing, existing *model.Listing,
+) bool {
+	if !needsDetail(mode, listing, existing) {
+		carryOverDetail(listing, existing)
+		return false
+	}
+
+	if err := detailScraper.ScrapeDetail(ctx, listing); err != nil {
+		s.logger.Warn("detail enrichment failed",
+			zap.String("url", listing.URL),
+			zap.Error(err))
+		carryOverDetail(listing, existing)
+		return false
+	}
+
+	return true
+}
+
+// needsDetail decides whether a listing should be enriched under mode
+func needsDetail(mode string, listing, existing *model.Listing) bool {
+	switch mode {
+	case detailModeAll:
+		return true
+	case detailModeNew:
+		return existing == nil
+	case detailModeChanged:
+		return existing == nil ||
+			existing.Price != listing.Price ||
+			existing.Title != listing.Title ||
+			existing.Location != listing.Location
+	default:
+		return false
+	}
+}
+
+// carryOverDetail fills detail fields that the card did not provide from the
+// stored copy of the listing
+func carryOverDetail(listing, existing *model.Listing) {
+	if existing == nil {
+		return
+	}
+	if listing.Description == "" {
+		listing.Description = existing.Description
+	}
+	if listing.AgentName == "" {
+		listing.AgentName = existing.AgentName
+	}
+	if listing.AgentPhone == "" {
+		listing.AgentPhone = existing.AgentPhone
+	}
+	if listing.Bedrooms == 0 {
+		listing.Bedrooms = existing.Bedrooms
+	}
+	if listing.Bathrooms == 0 {
+		listing.Bathrooms = existing.Bathrooms
+	}
+	if listing.LandArea == 0 {
+		listing.LandArea = existing.LandArea
+	}
+	if listing.BuildingArea == 0 {
+		listing.BuildingArea = existing.BuildingArea
+	}
+	if len(listing.Images) == 0 {
+		listing.Images = existing.Images
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// defaultDelistAfterRuns is the number of consecutive full crawls a listing
+// may be missing from before it is marked inactive
+const defaultDelistAfterRuns = 3
+
+// markUnseen updates the lifecycle of listings the job did not see. Only a
+// complete crawl from the site's base URL covers the whole search, so partial
+// or ad-hoc crawls never count as a missed run.
+func (s *ScraperService) markUnseen(ctx context.Context, job *model.JobStatus, startURL string) {
+	cfg, ok := s.sites[job.SiteName]
+	if !ok || startURL != cfg.BaseURL {
+		return
+	}
+
+	threshold := cfg.DelistAfterRuns
+	if threshold <= 0 {
+		threshold = defaultDelistAfterRuns
+	}
+
+	delisted, err := s.repository.MarkUnseen(ctx, job.SiteName, job.JobID, threshold)
+	if err != nil {
+		s.logger.Error("failed to update listing lifecycle",
+			zap.String("site", job.SiteName),
+			zap.Error(err))
+		return
+	}
+
+	job.Delisted = int(delisted)
+	if delisted > 0 {
+		s.logger.Info("listings delisted",
+			zap.String("site", job.SiteName),
+			zap.Int64("count", delisted))
+	}
+}
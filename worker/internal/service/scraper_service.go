@@ -2,19 +2,42 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
 	"github.com/Alwanly/Houses-Prices/worker/internal/model"
 	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
 )
 
+// ErrListingNotFound is returned when a listing ID does not match any stored listing
+var ErrListingNotFound = errors.New("listing not found")
+
+// ErrJobNotFound is returned when no scrape run matches a job ID or site
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrSiteNotFound is returned for a site without a registered scraper
+var ErrSiteNotFound = errors.New("site not found")
+
+// Cancellation causes that RunJob records with their own run status rather
+// than as a failure
+var (
+	ErrJobCancelled   = errors.New("job cancelled")
+	ErrJobInterrupted = errors.New("job interrupted by shutdown")
+)
+
 // ScraperService orchestrates scraping operations
 type ScraperService struct {
 	scrapers   map[string]Scraper
+	sites      map[string]*config.SiteConfig
 	repository storage.ListingRepository
+	runs       storage.RunRepository
 	notifier   Notifier
+	workerID   string
 	logger     *zap.Logger
 }
 
@@ -29,16 +52,23 @@ type Notifier interface {
 	NotifySuccess(ctx context.Context, siteName string, count int) error
 }
 
-// NewScraperService creates a new scraper service
+// NewScraperService creates a new scraper service. runs may be nil, in which
+// case scrape runs are not recorded. workerID identifies this worker in the
+// runs it records.
 func NewScraperService(
 	repository storage.ListingRepository,
+	runs storage.RunRepository,
 	notifier Notifier,
+	workerID string,
 	logger *zap.Logger,
 ) *ScraperService {
 	return &ScraperService{
 		scrapers:   make(map[string]Scraper),
+		sites:      make(map[string]*config.SiteConfig),
 		repository: repository,
+		runs:       runs,
 		notifier:   notifier,
+		workerID:   workerID,
 		logger:     logger,
 	}
 }
@@ -49,51 +79,235 @@ func (s *ScraperService) RegisterScraper(name string, scraper Scraper) {
 	s.logger.Info("scraper registered", zap.String("site", name))
 }
 
-// ScrapeWebsite performs complete scraping workflow for a site
-func (s *ScraperService) ScrapeWebsite(ctx context.Context, siteName, url string) error {
+// RegisterSite registers a scraper together with the configuration of its site,
+// which supplies the base URL and crawl budget
+func (s *ScraperService) RegisterSite(cfg *config.SiteConfig, scraper Scraper) {
+	s.sites[cfg.Name] = cfg
+	s.RegisterScraper(cfg.Name, scraper)
+}
+
+// NewJob creates the run record for a scrape of siteName starting at url
+func (s *ScraperService) NewJob(siteName, url, trigger string) *model.JobStatus {
+	return &model.JobStatus{
+		JobID:    primitive.NewObjectID().Hex(),
+		SiteName: siteName,
+		URL:      url,
+		Trigger:  trigger,
+		Attempt:  1,
+	}
+}
+
+// QueueJob records job as queued for a later RunJob
+func (s *ScraperService) QueueJob(ctx context.Context, job *model.JobStatus, queuedAt time.Time) error {
+	job.Status = model.JobStatusQueued
+	job.QueuedAt = queuedAt
+	if s.runs == nil {
+		return nil
+	}
+	return s.runs.Save(ctx, job)
+}
+
+// HasSite reports whether a scraper is registered for siteName
+func (s *ScraperService) HasSite(siteName string) bool {
+	_, ok := s.scrapers[siteName]
+	return ok
+}
+
+// ScrapeWebsite runs a manually triggered scrape of a site. See RunJob.
+func (s *ScraperService) ScrapeWebsite(ctx context.Context, siteName, url string) (*model.JobStatus, error) {
+	job := s.NewJob(siteName, url, model.JobTriggerManual)
+	err := s.RunJob(ctx, job)
+	return job, err
+}
+
+// RunJob crawls job.SiteName starting at job.URL and follows pagination until
+// the site's crawl budget is spent or no next page remains. An empty URL
+// starts from the site's configured base URL. The run record is stored when
+// the job starts, after every page and when it ends.
+func (s *ScraperService) RunJob(ctx context.Context, job *model.JobStatus) error {
+	siteName := job.SiteName
+
+	job.WorkerID = s.workerID
+	job.Status = model.JobStatusRunning
+	job.StartTime = time.Now()
+
 	scraper, ok := s.scrapers[siteName]
 	if !ok {
-		return fmt.Errorf("scraper not found for site: %s", siteName)
+		return s.failJob(ctx, job, fmt.Errorf("scraper not found for site: %s", siteName))
 	}
 
-	s.logger.Info("starting scrape job", zap.String("site", siteName), zap.String("url", url))
+	if job.URL == "" {
+		if cfg, ok := s.sites[siteName]; ok {
+			job.URL = cfg.BaseURL
+		}
+	}
+	if job.URL == "" {
+		return s.failJob(ctx, job, fmt.Errorf("no url to scrape for site: %s", siteName))
+	}
 
-	// Scrape
-	result, err := scraper.Scrape(ctx, url)
+	s.logger.Info("starting scrape job",
+		zap.String("site", siteName),
+		zap.String("job_id", job.JobID),
+		zap.String("url", job.URL))
+	s.saveRun(ctx, job)
+
+	complete, err := s.crawl(ctx, scraper, job, job.URL)
 	if err != nil {
-		if s.notifier != nil {
+		// Report why the run was cancelled, e.g. a lost lock lease, rather
+		// than the bare context error
+		if cause := context.Cause(ctx); cause != nil {
+			err = cause
+		}
+		if s.notifier != nil && stoppedStatus(err) == model.JobStatusFailed {
 			s.notifier.NotifyError(ctx, siteName, err)
 		}
-		return fmt.Errorf("scraping %s: %w", siteName, err)
+		return s.failJob(ctx, job, fmt.Errorf("scraping %s: %w", siteName, err))
 	}
 
-	// Save each listing
-	savedCount := 0
-	for _, listing := range result.Listings {
-		listing.SiteName = siteName
-
-		if err := s.repository.Save(ctx, listing); err != nil {
-			s.logger.Error("failed to save listing", zap.String("url", listing.URL), zap.Error(err))
-			continue
-		}
-		savedCount++
+	if complete {
+		s.markUnseen(ctx, job, job.URL)
 	}
 
+	s.finishJob(ctx, job, model.JobStatusCompleted)
+
 	s.logger.Info("scrape job completed",
 		zap.String("site", siteName),
-		zap.Int("scraped", len(result.Listings)),
-		zap.Int("saved", savedCount),
-		zap.Int("errors", result.ErrorCount))
+		zap.String("job_id", job.JobID),
+		zap.Int("pages", len(job.Pages)),
+		zap.Int("scraped", job.Scraped),
+		zap.Int("saved", job.Saved),
+		zap.Int("errors", job.Errors),
+		zap.Int("delisted", job.Delisted),
+		zap.String("message", job.Message))
 
 	// Notify success
 	if s.notifier != nil {
-		s.notifier.NotifySuccess(ctx, siteName, savedCount)
+		s.notifier.NotifySuccess(ctx, siteName, job.Saved)
 	}
 
 	return nil
 }
 
+// AbortJob records a job that ends without running, e.g. a queued job that
+// was cancelled, with the run status matching err
+func (s *ScraperService) AbortJob(ctx context.Context, job *model.JobStatus, err error) {
+	job.WorkerID = s.workerID
+	if job.StartTime.IsZero() {
+		job.StartTime = time.Now()
+	}
+	s.failJob(ctx, job, err)
+}
+
+// failJob records err as the final message of a stopped run and returns it
+func (s *ScraperService) failJob(ctx context.Context, job *model.JobStatus, err error) error {
+	job.Message = err.Error()
+	s.finishJob(ctx, job, stoppedStatus(err))
+	return err
+}
+
+// stoppedStatus is the run status of a job that ended with err
+func stoppedStatus(err error) string {
+	switch {
+	case errors.Is(err, ErrJobCancelled):
+		return model.JobStatusCancelled
+	case errors.Is(err, ErrJobInterrupted):
+		return model.JobStatusInterrupted
+	default:
+		return model.JobStatusFailed
+	}
+}
+
+func (s *ScraperService) finishJob(ctx context.Context, job *model.JobStatus, status string) {
+	job.Status = status
+	job.EndTime = time.Now()
+	job.Duration = job.EndTime.Sub(job.StartTime).Seconds()
+	s.saveRun(ctx, job)
+}
+
+// saveRun stores the run record. Failures are logged only, losing a progress
+// update must not abort the scrape itself.
+func (s *ScraperService) saveRun(ctx context.Context, job *model.JobStatus) {
+	if s.runs == nil {
+		return
+	}
+	if err := s.runs.Save(context.WithoutCancel(ctx), job); err != nil {
+		s.logger.Warn("failed to save run",
+			zap.String("site", job.SiteName),
+			zap.String("job_id", job.JobID),
+			zap.Error(err))
+	}
+}
+
 // GetListings retrieves listings with filters
 func (s *ScraperService) GetListings(ctx context.Context, filter *storage.ListingFilter) ([]*model.Listing, error) {
 	return s.repository.FindAll(ctx, filter)
 }
+
+// CountListings counts the listings matching filter, ignoring its limit and offset
+func (s *ScraperService) CountListings(ctx context.Context, filter *storage.ListingFilter) (int64, error) {
+	return s.repository.Count(ctx, filter)
+}
+
+// GetPriceHistory returns the recorded price changes of a listing, newest first
+func (s *ScraperService) GetPriceHistory(ctx context.Context, listingID string) ([]*model.PriceChange, error) {
+	listing, err := s.repository.FindByID(ctx, listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return nil, ErrListingNotFound
+	}
+
+	return s.repository.FindPriceHistory(ctx, listingID)
+}
+
+// GetJob returns the run record with the given job ID
+func (s *ScraperService) GetJob(ctx context.Context, jobID string) (*model.JobStatus, error) {
+	if s.runs == nil {
+		return nil, ErrJobNotFound
+	}
+
+	job, err := s.runs.FindByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// ListJobs returns run records matching filter, newest first
+func (s *ScraperService) ListJobs(ctx context.Context, filter *storage.RunFilter) ([]*model.JobStatus, error) {
+	if s.runs == nil {
+		return nil, nil
+	}
+	return s.runs.FindAll(ctx, filter)
+}
+
+// CountJobs counts the run records matching filter, ignoring its limit and offset
+func (s *ScraperService) CountJobs(ctx context.Context, filter *storage.RunFilter) (int64, error) {
+	if s.runs == nil {
+		return 0, nil
+	}
+	return s.runs.Count(ctx, filter)
+}
+
+// LastRun returns the most recent run of a registered site
+func (s *ScraperService) LastRun(ctx context.Context, siteName string) (*model.JobStatus, error) {
+	if !s.HasSite(siteName) {
+		return nil, ErrSiteNotFound
+	}
+	if s.runs == nil {
+		return nil, ErrJobNotFound
+	}
+
+	job, err := s.runs.FindLastBySite(ctx, siteName)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
@@ -5,13 +5,19 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/Alwanly/Houses-Prices/worker/internal/config"
 	"github.com/Alwanly/Houses-Prices/worker/internal/model"
 	"github.com/Alwanly/Houses-Prices/worker/internal/storage"
 	"go.uber.org/zap"
 )
 
 type mockRepo struct {
-	saved []*model.Listing
+	saved    []*model.Listing
+	existing map[string]*model.Listing
+	unseen   []string // run IDs passed to MarkUnseen
+	batches  []int    // sizes of SaveMany calls
+	lookups  int      // FindByURLs calls
+	saveErr  error    // returned by SaveMany when set
 }
 
 func (m *mockRepo) Save(ctx context.Context, listing *model.Listing) error {
@@ -19,10 +25,42 @@ func (m *mockRepo) Save(ctx context.Context, listing *model.Listing) error {
 	return nil
 }
 
-func (m *mockRepo) FindByURL(ctx context.Context, url string) (*model.Listing, error) {
+func (m *mockRepo) SaveMany(ctx context.Context, listings []*model.Listing) (*storage.SaveResult, error) {
+	m.batches = append(m.batches, len(listings))
+	if m.saveErr != nil {
+		return nil, m.saveErr
+	}
+	result := &storage.SaveResult{
+		Outcomes: make([]storage.SaveOutcome, len(listings)),
+		Errors:   make([]error, len(listings)),
+	}
+	for i, l := range listings {
+		m.saved = append(m.saved, l)
+		result.Outcomes[i] = storage.SaveInserted
+		result.Inserted++
+	}
+	return result, nil
+}
+
+func (m *mockRepo) FindByID(ctx context.Context, id string) (*model.Listing, error) {
 	return nil, nil
 }
 
+func (m *mockRepo) FindByURL(ctx context.Context, url string) (*model.Listing, error) {
+	return m.existing[url], nil
+}
+
+func (m *mockRepo) FindByURLs(ctx context.Context, urls []string) (map[string]*model.Listing, error) {
+	m.lookups++
+	found := make(map[string]*model.Listing)
+	for _, u := range urls {
+		if l, ok := m.existing[u]; ok {
+			found[u] = l
+		}
+	}
+	return found, nil
+}
+
 func (m *mockRepo) FindAll(ctx context.Context, f *storage.ListingFilter) ([]*model.Listing, error) {
 	return m.saved, nil
 }
@@ -31,6 +69,19 @@ func (m *mockRepo) UpdatePrice(ctx context.Context, url string, newPrice float64
 	return nil
 }
 
+func (m *mockRepo) FindPriceHistory(ctx context.Context, listingID string) ([]*model.PriceChange, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) MarkUnseen(ctx context.Context, siteName, runID string, threshold int) (int64, error) {
+	m.unseen = append(m.unseen, runID)
+	return 0, nil
+}
+
+func (m *mockRepo) BackfillAddresses(ctx context.Context, resolve func(string) *model.Address) (*storage.BackfillResult, error) {
+	return &storage.BackfillResult{}, nil
+}
+
 func (m *mockRepo) Count(ctx context.Context, f *storage.ListingFilter) (int64, error) {
 	return int64(len(m.saved)), nil
 }
@@ -73,11 +124,11 @@ func TestScrapeWebsite_Success(t *testing.T) {
 	ctx := context.Background()
 	repo := &mockRepo{}
 	notifier := &mockNotifier{}
-	svc := NewScraperService(repo, notifier, zap.NewNop())
+	svc := NewScraperService(repo, nil, notifier, "test-worker", zap.NewNop())
 
 	svc.RegisterScraper("testsite", &fakeScraperSuccess{})
 
-	if err := svc.ScrapeWebsite(ctx, "testsite", "http://example.com"); err != nil {
+	if _, err := svc.ScrapeWebsite(ctx, "testsite", "http://example.com"); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -94,9 +145,9 @@ func TestScrapeWebsite_NotFound(t *testing.T) {
 	ctx := context.Background()
 	repo := &mockRepo{}
 	notifier := &mockNotifier{}
-	svc := NewScraperService(repo, notifier, zap.NewNop())
+	svc := NewScraperService(repo, nil, notifier, "test-worker", zap.NewNop())
 
-	if err := svc.ScrapeWebsite(ctx, "nosite", "http://example.com"); err == nil {
+	if _, err := svc.ScrapeWebsite(ctx, "nosite", "http://example.com"); err == nil {
 		t.Fatalf("expected error for missing scraper, got nil")
 	}
 }
@@ -105,11 +156,11 @@ func TestScrapeWebsite_ScrapeError_NotifierCalled(t *testing.T) {
 	ctx := context.Background()
 	repo := &mockRepo{}
 	notifier := &mockNotifier{}
-	svc := NewScraperService(repo, notifier, zap.NewNop())
+	svc := NewScraperService(repo, nil, notifier, "test-worker", zap.NewNop())
 
 	svc.RegisterScraper("badsite", &fakeScraperError{})
 
-	if err := svc.ScrapeWebsite(ctx, "badsite", "http://example.com"); err == nil {
+	if _, err := svc.ScrapeWebsite(ctx, "badsite", "http://example.com"); err == nil {
 		t.Fatalf("expected error from scraper, got nil")
 	}
 
@@ -117,3 +168,520 @@ func TestScrapeWebsite_ScrapeError_NotifierCalled(t *testing.T) {
 		t.Fatalf("expected notifier to be called with error")
 	}
 }
+
+// fakePaginatedScraper serves pages keyed by URL, each linking to the next
+type fakePaginatedScraper struct {
+	pages   map[string]*model.ScrapeResult
+	visited []string
+}
+
+func (f *fakePaginatedScraper) Scrape(ctx context.Context, url string) (*model.ScrapeResult, error) {
+	f.visited = append(f.visited, url)
+	page, ok := f.pages[url]
+	if !ok {
+		return nil, errors.New("page not found")
+	}
+	return page, nil
+}
+
+func newPage(next string, urls ...string) *model.ScrapeResult {
+	listings := make([]*model.Listing, 0, len(urls))
+	for _, u := range urls {
+		listings = append(listings, &model.Listing{URL: u, Title: "Test", Price: 100})
+	}
+	return &model.ScrapeResult{
+		Listings:     listings,
+		TotalScraped: len(listings),
+		NextPageURL:  next,
+		HasNextPage:  next != "",
+	}
+}
+
+func TestScrapeWebsite_FollowsPagination(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+	scraper := &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("http://example.com/p2", "a", "b"),
+		"http://example.com/p2": newPage("http://example.com/p3", "c", "d"),
+		"http://example.com/p3": newPage("", "e"),
+	}}
+	svc.RegisterScraper("testsite", scraper)
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "http://example.com/p1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(job.Pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(job.Pages))
+	}
+	if job.Saved != 5 || len(repo.saved) != 5 {
+		t.Fatalf("expected 5 saved listings, got job=%d repo=%d", job.Saved, len(repo.saved))
+	}
+	if job.Pages[1].Saved != 2 {
+		t.Fatalf("expected 2 saved on page 2, got %d", job.Pages[1].Saved)
+	}
+}
+
+func TestScrapeWebsite_RespectsBudget(t *testing.T) {
+	pages := map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("http://example.com/p2", "a", "b"),
+		"http://example.com/p2": newPage("http://example.com/p3", "c", "d"),
+		"http://example.com/p3": newPage("", "e"),
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.SiteConfig
+		wantPages int
+		wantSaved int
+	}{
+		{name: "max pages", cfg: config.SiteConfig{MaxPages: 2}, wantPages: 2, wantSaved: 4},
+		{name: "max listings", cfg: config.SiteConfig{MaxListings: 3}, wantPages: 2, wantSaved: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{}
+			svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+			cfg := tt.cfg
+			cfg.Name = "testsite"
+			cfg.BaseURL = "http://example.com/p1"
+			svc.RegisterSite(&cfg, &fakePaginatedScraper{pages: pages})
+
+			job, err := svc.ScrapeWebsite(context.Background(), "testsite", "")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(job.Pages) != tt.wantPages {
+				t.Fatalf("expected %d pages, got %d", tt.wantPages, len(job.Pages))
+			}
+			if job.Saved != tt.wantSaved {
+				t.Fatalf("expected %d saved, got %d", tt.wantSaved, job.Saved)
+			}
+		})
+	}
+}
+
+func TestScrapeWebsite_DetectsPaginationLoop(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+	scraper := &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+		"http://example.com/p1":  newPage("http://example.com/p2", "a"),
+		"http://example.com/p2":  newPage("http://example.com/p1/", "b"),
+		"http://example.com/p1/": newPage("", "a"),
+	}}
+	svc.RegisterScraper("testsite", scraper)
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "http://example.com/p1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(scraper.visited) != 2 {
+		t.Fatalf("expected crawl to stop after 2 pages, visited %v", scraper.visited)
+	}
+	if job.Message == "" {
+		t.Fatalf("expected loop to be reported in job message")
+	}
+}
+
+func TestScrapeWebsite_RepeatedLastPageCompletesCrawl(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+	scraper := &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("http://example.com/p2", "a"),
+		"http://example.com/p2": newPage("http://example.com/p3", "b"),
+		"http://example.com/p3": newPage("http://example.com/p4", "b"),
+	}}
+	svc.RegisterSite(&config.SiteConfig{Name: "testsite", BaseURL: "http://example.com/p1"}, scraper)
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(scraper.visited) != 3 {
+		t.Fatalf("expected crawl to stop at the repeated page, visited %v", scraper.visited)
+	}
+	if len(repo.unseen) != 1 || repo.unseen[0] != job.JobID {
+		t.Fatalf("expected the crawl to count as complete, got unseen calls %v", repo.unseen)
+	}
+}
+
+func TestScrapeWebsite_FailedSaveSkipsMarkUnseen(t *testing.T) {
+	repo := &mockRepo{saveErr: errors.New("mongo unavailable")}
+	svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+	svc.RegisterSite(&config.SiteConfig{Name: "testsite", BaseURL: "http://example.com/p1"}, &fakePaginatedScraper{
+		pages: map[string]*model.ScrapeResult{
+			"http://example.com/p1": newPage("", "a", "b"),
+		},
+	})
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(repo.unseen) != 0 {
+		t.Fatalf("expected no lifecycle update after failed saves, got unseen calls %v", repo.unseen)
+	}
+	if job.Errors != 2 {
+		t.Fatalf("expected 2 errors, got %d", job.Errors)
+	}
+}
+
+// fakeDetailScraper serves a single card page and records detail visits
+type fakeDetailScraper struct {
+	fakePaginatedScraper
+	detailVisits []string
+}
+
+func (f *fakeDetailScraper) ScrapeDetail(ctx context.Context, listing *model.Listing) error {
+	f.detailVisits = append(f.detailVisits, listing.URL)
+	listing.Description = "detail of " + listing.URL
+	return nil
+}
+
+func TestScrapeWebsite_DetailEnrichment(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantVisits  int
+		wantOldDesc string
+	}{
+		{name: "all", mode: "all", wantVisits: 2, wantOldDesc: "detail of old"},
+		{name: "new only", mode: "new", wantVisits: 1, wantOldDesc: "stored description"},
+		{name: "changed", mode: "changed", wantVisits: 1, wantOldDesc: "stored description"},
+		{name: "off", mode: "off", wantVisits: 0, wantOldDesc: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{existing: map[string]*model.Listing{
+				"old": {URL: "old", Title: "Test", Price: 100, Description: "stored description"},
+			}}
+			svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+			scraper := &fakeDetailScraper{fakePaginatedScraper: fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+				"http://example.com/p1": newPage("", "old", "new"),
+			}}}
+			svc.RegisterSite(&config.SiteConfig{
+				Name:            "testsite",
+				BaseURL:         "http://example.com/p1",
+				DetailMode:      tt.mode,
+				DetailSelectors: config.DetailSelectorConfig{Description: ".description"},
+			}, scraper)
+
+			job, err := svc.ScrapeWebsite(context.Background(), "testsite", "")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(scraper.detailVisits) != tt.wantVisits {
+				t.Fatalf("expected %d detail visits, got %v", tt.wantVisits, scraper.detailVisits)
+			}
+			if tt.mode != "off" && repo.lookups != 1 {
+				t.Fatalf("expected one lookup of stored listings per batch, got %d", repo.lookups)
+			}
+			if job.Pages[0].Enriched != tt.wantVisits {
+				t.Fatalf("expected %d enriched, got %d", tt.wantVisits, job.Pages[0].Enriched)
+			}
+			if repo.saved[0].Description != tt.wantOldDesc {
+				t.Fatalf("expected stored listing description %q, got %q", tt.wantOldDesc, repo.saved[0].Description)
+			}
+		})
+	}
+}
+
+// fakeStreamScraper emits listings one by one and fails on configured pages
+type fakeStreamScraper struct {
+	pages  map[string]*model.ScrapeResult
+	failAt map[string]int // page URL -> number of listings emitted before failing
+}
+
+func (f *fakeStreamScraper) Scrape(ctx context.Context, url string) (*model.ScrapeResult, error) {
+	return nil, errors.New("buffered scrape must not be used")
+}
+
+func (f *fakeStreamScraper) ScrapeStream(
+	ctx context.Context,
+	url string,
+	onListing func(*model.Listing) error,
+	onProgress func(model.ScrapeProgress),
+) (*model.ScrapeResult, error) {
+	page, ok := f.pages[url]
+	if !ok {
+		return nil, errors.New("page not found")
+	}
+
+	failAt, fails := f.failAt[url]
+	for i, listing := range page.Listings {
+		if fails && i == failAt {
+			return nil, errors.New("connection reset")
+		}
+		if err := onListing(listing); err != nil {
+			return nil, err
+		}
+		onProgress(model.ScrapeProgress{URL: url, Scraped: i + 1})
+	}
+
+	return &model.ScrapeResult{
+		TotalScraped: len(page.Listings),
+		NextPageURL:  page.NextPageURL,
+		HasNextPage:  page.HasNextPage,
+	}, nil
+}
+
+func TestScrapeWebsite_StreamingSavesPartialProgress(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+	scraper := &fakeStreamScraper{
+		pages: map[string]*model.ScrapeResult{
+			"http://example.com/p1": newPage("http://example.com/p2", "a", "b", "c"),
+			"http://example.com/p2": newPage("http://example.com/p3", "d", "e", "f"),
+			"http://example.com/p3": newPage("", "g"),
+		},
+		failAt: map[string]int{"http://example.com/p2": 2},
+	}
+	svc.RegisterSite(&config.SiteConfig{
+		Name:         "testsite",
+		BaseURL:      "http://example.com/p1",
+		StreamBuffer: 1,
+	}, scraper)
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(repo.saved) != 5 {
+		t.Fatalf("expected the 5 listings emitted before the failure to be saved, got %d", len(repo.saved))
+	}
+	if job.Saved != 5 || job.Scraped != 5 {
+		t.Fatalf("expected scraped=5 saved=5, got scraped=%d saved=%d", job.Scraped, job.Saved)
+	}
+	if len(job.Pages) != 2 || job.Pages[1].Saved != 2 {
+		t.Fatalf("expected 2 pages with 2 saved on the failed page, got %+v", job.Pages)
+	}
+	if job.Message == "" {
+		t.Fatalf("expected failure to be reported in job message")
+	}
+}
+
+func TestGetPriceHistory_UnknownListing(t *testing.T) {
+	svc := NewScraperService(&mockRepo{}, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+
+	if _, err := svc.GetPriceHistory(context.Background(), "missing"); !errors.Is(err, ErrListingNotFound) {
+		t.Fatalf("expected ErrListingNotFound, got %v", err)
+	}
+}
+
+func TestScrapeWebsite_MarksUnseenOnlyAfterFullCrawl(t *testing.T) {
+	pages := map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("http://example.com/p2", "a", "b"),
+		"http://example.com/p2": newPage("", "c"),
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		maxPages   int
+		wantMarked bool
+	}{
+		{name: "full crawl", url: "", wantMarked: true},
+		{name: "budget cut short", url: "", maxPages: 1, wantMarked: false},
+		{name: "ad-hoc url", url: "http://example.com/p2", wantMarked: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{}
+			svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+			svc.RegisterSite(&config.SiteConfig{
+				Name:     "testsite",
+				BaseURL:  "http://example.com/p1",
+				MaxPages: tt.maxPages,
+			}, &fakePaginatedScraper{pages: pages})
+
+			job, err := svc.ScrapeWebsite(context.Background(), "testsite", tt.url)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			marked := len(repo.unseen) == 1 && repo.unseen[0] == job.JobID
+			if marked != tt.wantMarked {
+				t.Fatalf("expected marked=%v, got unseen calls %v", tt.wantMarked, repo.unseen)
+			}
+			for _, l := range repo.saved {
+				if l.RunID != job.JobID {
+					t.Fatalf("expected saved listing to carry run ID %s, got %s", job.JobID, l.RunID)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeWebsite_SavesInBatches(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewScraperService(repo, nil, &mockNotifier{}, "test-worker", zap.NewNop())
+	svc.RegisterSite(&config.SiteConfig{
+		Name:          "testsite",
+		BaseURL:       "http://example.com/p1",
+		SaveBatchSize: 2,
+	}, &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("", "a", "b", "c", "d", "e"),
+	}})
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	total := 0
+	for _, n := range repo.batches {
+		if n > 2 {
+			t.Fatalf("expected batches of at most 2, got %v", repo.batches)
+		}
+		total += n
+	}
+	if total != 5 || job.Inserted != 5 {
+		t.Fatalf("expected 5 inserted listings, got total=%d inserted=%d", total, job.Inserted)
+	}
+}
+
+type mockRunRepo struct {
+	saves []model.JobStatus // copies of every saved record, in order
+}
+
+func (m *mockRunRepo) Save(ctx context.Context, run *model.JobStatus) error {
+	m.saves = append(m.saves, *run)
+	return nil
+}
+
+func (m *mockRunRepo) FindByID(ctx context.Context, id string) (*model.JobStatus, error) {
+	for i := len(m.saves) - 1; i >= 0; i-- {
+		if m.saves[i].JobID == id {
+			run := m.saves[i]
+			return &run, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRunRepo) FindAll(ctx context.Context, f *storage.RunFilter) ([]*model.JobStatus, error) {
+	return nil, nil
+}
+
+func (m *mockRunRepo) FindLastBySite(ctx context.Context, siteName string) (*model.JobStatus, error) {
+	return nil, nil
+}
+
+func (m *mockRunRepo) Count(ctx context.Context, f *storage.RunFilter) (int64, error) {
+	return 0, nil
+}
+
+func TestScrapeWebsite_RecordsRun(t *testing.T) {
+	runs := &mockRunRepo{}
+	svc := NewScraperService(&mockRepo{}, runs, &mockNotifier{}, "test-worker", zap.NewNop())
+	svc.RegisterScraper("testsite", &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("http://example.com/p2", "a", "b"),
+		"http://example.com/p2": newPage("", "c"),
+	}})
+
+	job, err := svc.ScrapeWebsite(context.Background(), "testsite", "http://example.com/p1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// start, one per page, end
+	if len(runs.saves) != 4 {
+		t.Fatalf("expected 4 run saves, got %d", len(runs.saves))
+	}
+	if first := runs.saves[0]; first.Status != model.JobStatusRunning || first.WorkerID != "test-worker" {
+		t.Fatalf("expected running record from test-worker first, got %+v", first)
+	}
+
+	stored, err := svc.GetJob(context.Background(), job.JobID)
+	if err != nil {
+		t.Fatalf("expected stored run, got %v", err)
+	}
+	if stored.Status != model.JobStatusCompleted || stored.Trigger != model.JobTriggerManual {
+		t.Fatalf("expected completed manual run, got %s/%s", stored.Status, stored.Trigger)
+	}
+	if len(stored.Pages) != 2 || stored.Saved != 3 || stored.EndTime.IsZero() {
+		t.Fatalf("unexpected final record %+v", stored)
+	}
+}
+
+func TestScrapeWebsite_RecordsFailedRun(t *testing.T) {
+	runs := &mockRunRepo{}
+	svc := NewScraperService(&mockRepo{}, runs, &mockNotifier{}, "test-worker", zap.NewNop())
+
+	if _, err := svc.ScrapeWebsite(context.Background(), "nosite", ""); err == nil {
+		t.Fatalf("expected error for missing scraper, got nil")
+	}
+
+	if len(runs.saves) != 1 {
+		t.Fatalf("expected 1 run save, got %d", len(runs.saves))
+	}
+	if run := runs.saves[0]; run.Status != model.JobStatusFailed || run.Message == "" {
+		t.Fatalf("expected failed run with message, got %+v", run)
+	}
+}
+
+func TestRunJob_ReportsCancellationCause(t *testing.T) {
+	runs := &mockRunRepo{}
+	svc := NewScraperService(&mockRepo{}, runs, &mockNotifier{}, "test-worker", zap.NewNop())
+	svc.RegisterScraper("testsite", &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+		"http://example.com/p1": newPage("", "a"),
+	}})
+
+	leaseLost := errors.New("lease lost")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(leaseLost)
+
+	job := svc.NewJob("testsite", "http://example.com/p1", model.JobTriggerScheduled)
+	job.FenceToken = 7
+	if err := svc.RunJob(ctx, job); !errors.Is(err, leaseLost) {
+		t.Fatalf("expected lease lost error, got %v", err)
+	}
+
+	last := runs.saves[len(runs.saves)-1]
+	if last.Status != model.JobStatusFailed || last.Message != "scraping testsite: lease lost" || last.FenceToken != 7 {
+		t.Fatalf("unexpected final record %+v", last)
+	}
+}
+
+func TestRunJob_RecordsStopStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		cause  error
+		status string
+	}{
+		{name: "cancelled", cause: ErrJobCancelled, status: model.JobStatusCancelled},
+		{name: "interrupted", cause: ErrJobInterrupted, status: model.JobStatusInterrupted},
+		{name: "other cause", cause: errors.New("max duration exceeded"), status: model.JobStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := &mockRunRepo{}
+			notifier := &mockNotifier{}
+			svc := NewScraperService(&mockRepo{}, runs, notifier, "test-worker", zap.NewNop())
+			svc.RegisterScraper("testsite", &fakePaginatedScraper{pages: map[string]*model.ScrapeResult{
+				"http://example.com/p1": newPage("", "a"),
+			}})
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(tt.cause)
+
+			job := svc.NewJob("testsite", "http://example.com/p1", model.JobTriggerManual)
+			if err := svc.RunJob(ctx, job); !errors.Is(err, tt.cause) {
+				t.Fatalf("expected %v, got %v", tt.cause, err)
+			}
+
+			if last := runs.saves[len(runs.saves)-1]; last.Status != tt.status {
+				t.Fatalf("expected status %s, got %s", tt.status, last.Status)
+			}
+			if notified := notifier.lastErr != nil; notified != (tt.status == model.JobStatusFailed) {
+				t.Fatalf("expected error notification only for failed runs, notified=%v", notified)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// SaveMany upserts listings by URL in a single unordered bulk write. Existing
+// documents are loaded with one query first so that price changes and
+// unchanged listings can be told apart, which keeps a batch at two round
+// trips plus one for the price history. A failing listing does not stop the
+// rest of the batch; only errors that affect the whole batch are returned.
+func (r *mongoListingRepository) SaveMany(ctx context.Context, listings []*model.Listing) (*SaveResult, error) {
+	result := &SaveResult{
+		Outcomes: make([]SaveOutcome, len(listings)),
+		Errors:   make([]error, len(listings)),
+	}
+	if len(listings) == 0 {
+		return result, nil
+	}
+
+	urls := make([]string, len(listings))
+	for i, l := range listings {
+		urls[i] = l.URL
+	}
+	existing, err := r.FindByURLs(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(listings))
+	writeIndex := make([]int, 0, len(listings)) // write position -> listing index
+	changes := make([]*model.PriceChange, len(listings))
+	superseded := supersededCopies(listings)
+
+	for i, listing := range listings {
+		// Only the last copy of a URL is saved, so that a listing seen twice
+		// in one batch is counted and its price change recorded once
+		if superseded[i] {
+			result.Outcomes[i] = SaveDuplicate
+			continue
+		}
+
+		old := existing[listing.URL]
+		if isStale(listing, old) {
+			result.Outcomes[i] = SaveStale
+			result.Errors[i] = ErrStaleFence
+			continue
+		}
+
+		outcome, change := prepareListing(listing, old, now)
+		result.Outcomes[i] = outcome
+		changes[i] = change
+
+		set, err := listingSetDoc(listing)
+		if err != nil {
+			result.Outcomes[i] = SaveFailed
+			result.Errors[i] = err
+			continue
+		}
+
+		writeIndex = append(writeIndex, i)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(listingWriteFilter(listing)).
+			SetUpdate(bson.M{
+				"$set":         set,
+				"$setOnInsert": bson.M{"created_at": listing.CreatedAt},
+				"$unset":       bson.M{"delisted_at": ""},
+			}).
+			SetUpsert(true))
+	}
+
+	if len(writes) > 0 {
+		if err := r.bulkWrite(ctx, listings, writes, writeIndex, result); err != nil {
+			return nil, err
+		}
+	}
+
+	history := make([]interface{}, 0)
+	for i, outcome := range result.Outcomes {
+		switch outcome {
+		case SaveInserted:
+			result.Inserted++
+		case SaveUpdated:
+			result.Updated++
+		case SaveUnchanged:
+			result.Unchanged++
+		case SaveFailed:
+			result.Failed++
+			continue
+		case SaveStale:
+			result.Stale++
+			continue
+		case SaveDuplicate:
+			continue
+		}
+		if changes[i] != nil {
+			history = append(history, changes[i])
+			result.PriceChanged++
+		}
+	}
+
+	if len(history) > 0 {
+		if _, err := r.priceChanges.InsertMany(ctx, history); err != nil {
+			return result, fmt.Errorf("recording price changes: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// duplicateKeyCode is the MongoDB error code of a unique index violation
+const duplicateKeyCode = 11000
+
+// bulkWrite runs an unordered bulk write and marks the listings whose write
+// failed individually
+func (r *mongoListingRepository) bulkWrite(
+	ctx context.Context,
+	listings []*model.Listing,
+	writes []mongo.WriteModel,
+	writeIndex []int,
+	result *SaveResult,
+) error {
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err == nil {
+		return nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return fmt.Errorf("bulk saving listings: %w", err)
+	}
+
+	for _, we := range bulkErr.WriteErrors {
+		i := writeIndex[we.Index]
+		// A fenced upsert that finds a newer write falls through to an
+		// insert, which collides with the stored URL
+		if we.HasErrorCode(duplicateKeyCode) && listings[i].FenceToken > 0 {
+			result.Outcomes[i] = SaveStale
+			result.Errors[i] = ErrStaleFence
+			continue
+		}
+		result.Outcomes[i] = SaveFailed
+		result.Errors[i] = we
+	}
+	return nil
+}
+
+// isStale reports whether existing was written under a newer fencing token
+// than the one listing is being saved with
+func isStale(listing, existing *model.Listing) bool {
+	return existing != nil && listing.FenceToken > 0 && existing.FenceToken > listing.FenceToken
+}
+
+// listingWriteFilter matches the stored copy of a listing unless it was
+// written under a newer fencing token
+func listingWriteFilter(listing *model.Listing) bson.M {
+	filter := bson.M{"url": listing.URL}
+	if listing.FenceToken > 0 {
+		filter["$or"] = bson.A{
+			bson.M{"fence_token": bson.M{"$exists": false}},
+			bson.M{"fence_token": bson.M{"$lte": listing.FenceToken}},
+		}
+	}
+	return filter
+}
+
+// supersededCopies marks the listings followed by another listing with the
+// same URL
+func supersededCopies(listings []*model.Listing) []bool {
+	superseded := make([]bool, len(listings))
+	seen := make(map[string]bool, len(listings))
+	for i := len(listings) - 1; i >= 0; i-- {
+		url := listings[i].URL
+		superseded[i] = seen[url]
+		seen[url] = true
+	}
+	return superseded
+}
+
+// FindByURLs loads the stored copies of the listings at urls keyed by URL.
+// URLs without a stored listing are missing from the map.
+func (r *mongoListingRepository) FindByURLs(ctx context.Context, urls []string) (map[string]*model.Listing, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"url": bson.M{"$in": urls}})
+	if err != nil {
+		return nil, fmt.Errorf("finding listings: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var found []*model.Listing
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("decoding listings: %w", err)
+	}
+
+	byURL := make(map[string]*model.Listing, len(found))
+	for _, l := range found {
+		byURL[l.URL] = l
+	}
+	return byURL, nil
+}
+
+// prepareListing sets the bookkeeping fields of a listing about to be saved
+// and works out what the save will do to the stored copy
+func prepareListing(listing, existing *model.Listing, now time.Time) (SaveOutcome, *model.PriceChange) {
+	listing.ScrapedAt = now
+
+	// A listing seen again is active, even if it had been delisted
+	listing.Status = model.ListingStatusActive
+	listing.MissedRuns = 0
+	listing.DelistedAt = nil
+
+	if existing == nil {
+		listing.CreatedAt = now
+		listing.UpdatedAt = now
+		return SaveInserted, nil
+	}
+
+	listing.CreatedAt = existing.CreatedAt
+
+	if listing.SameContent(existing) && existing.Status != model.ListingStatusInactive {
+		listing.UpdatedAt = existing.UpdatedAt
+		return SaveUnchanged, nil
+	}
+	listing.UpdatedAt = now
+
+	// A listing that moved between sale and rent starts a new price history,
+	// and a price of zero is unknown rather than a change
+	if existing.Price <= 0 || listing.Price <= 0 || existing.Price == listing.Price || !listing.SamePriceBasis(existing) {
+		return SaveUpdated, nil
+	}
+
+	listing.PreviousPrice = existing.Price
+	listing.PriceChangedAt = &now
+	return SaveUpdated, &model.PriceChange{
+		ListingID: existing.ID,
+		URL:       listing.URL,
+		SiteName:  listing.SiteName,
+		OldPrice:  existing.Price,
+		NewPrice:  listing.Price,
+		ChangedAt: now,
+		RunID:     listing.RunID,
+	}
+}
+
+// listingSetDoc converts a listing into a $set document without the fields
+// that must not be overwritten on update
+func listingSetDoc(listing *model.Listing) (bson.M, error) {
+	data, err := bson.Marshal(listing)
+	if err != nil {
+		return nil, fmt.Errorf("encoding listing: %w", err)
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("encoding listing: %w", err)
+	}
+
+	delete(doc, "_id")
+	delete(doc, "created_at")
+	return doc, nil
+}
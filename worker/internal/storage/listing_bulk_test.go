@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+func TestSupersededCopies(t *testing.T) {
+	tests := []struct {
+		name string
+		urls []string
+		want []bool
+	}{
+		{name: "empty", urls: nil, want: []bool{}},
+		{name: "distinct", urls: []string{"a", "b", "c"}, want: []bool{false, false, false}},
+		{name: "last copy kept", urls: []string{"a", "b", "a"}, want: []bool{true, false, false}},
+		{name: "three copies", urls: []string{"a", "a", "b", "a"}, want: []bool{true, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listings := make([]*model.Listing, len(tt.urls))
+			for i, u := range tt.urls {
+				listings[i] = &model.Listing{URL: u}
+			}
+			if got := supersededCopies(listings); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrepareListingUnknownPrice(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		oldPrice   float64
+		newPrice   float64
+		wantChange bool
+	}{
+		{name: "price changed", oldPrice: 100, newPrice: 90, wantChange: true},
+		{name: "price now negotiable", oldPrice: 100, newPrice: 0},
+		{name: "price was negotiable", oldPrice: 0, newPrice: 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &model.Listing{URL: "a", Title: "Rumah", Price: tt.oldPrice, CreatedAt: now.Add(-time.Hour)}
+			listing := &model.Listing{URL: "a", Title: "Rumah", Price: tt.newPrice}
+
+			outcome, change := prepareListing(listing, existing, now)
+			if outcome != SaveUpdated {
+				t.Fatalf("expected an update, got %s", outcome)
+			}
+			if (change != nil) != tt.wantChange {
+				t.Fatalf("expected price change %v, got %+v", tt.wantChange, change)
+			}
+		})
+	}
+}
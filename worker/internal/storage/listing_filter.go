@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"regexp"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+// ListingSortFields lists the fields listings can be sorted by
+var ListingSortFields = []string{
+	"scraped_at",
+	"created_at",
+	"updated_at",
+	"price",
+	"land_area",
+	"building_area",
+	"bedrooms",
+	"bathrooms",
+}
+
+// listingQuery translates a filter into a MongoDB query; FindAll and Count
+// share it so totals always match the listed items
+func listingQuery(f *ListingFilter, now time.Time) bson.M {
+	filter := bson.M{}
+	if f == nil {
+		return filter
+	}
+
+	if f.SiteName != "" {
+		filter["site_name"] = f.SiteName
+	}
+	switch f.ListingType {
+	case model.ListingTypeSale:
+		filter["listing_type"] = bson.M{"$ne": model.ListingTypeRent}
+	case model.ListingTypeRent:
+		filter["listing_type"] = model.ListingTypeRent
+	}
+	if f.RentPeriod != "" {
+		filter["rent_period"] = f.RentPeriod
+	}
+	if f.PropertyType != "" {
+		filter["property_type"] = f.PropertyType
+	}
+	for field, code := range map[string]string{
+		"address.province_code": f.ProvinceCode,
+		"address.city_code":     f.CityCode,
+		"address.district_code": f.DistrictCode,
+		"address.village_code":  f.VillageCode,
+	} {
+		if code != "" {
+			filter[field] = code
+		}
+	}
+	// Listings without a known price ("Harga Nego") have price zero and are
+	// left out of price filters and sorting
+	if f.MinPrice > 0 || f.MaxPrice > 0 || f.SortBy == "price" {
+		priceFilter := bson.M{"$gt": 0}
+		if f.MinPrice > 0 {
+			priceFilter["$gte"] = f.MinPrice
+		}
+		if f.MaxPrice > 0 {
+			priceFilter["$lte"] = f.MaxPrice
+		}
+		filter["price"] = priceFilter
+	}
+	if f.Location != "" {
+		// A case-insensitive substring match; the value is quoted so callers
+		// cannot run arbitrary patterns against the database
+		filter["location"] = bson.M{"$regex": regexp.QuoteMeta(f.Location), "$options": "i"}
+	}
+	if f.MinBedrooms > 0 {
+		filter["bedrooms"] = bson.M{"$gte": f.MinBedrooms}
+	}
+	if f.MinBathrooms > 0 {
+		filter["bathrooms"] = bson.M{"$gte": f.MinBathrooms}
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+
+	var exprs bson.A
+	if f.PriceDroppedWithinDays > 0 {
+		since := now.AddDate(0, 0, -f.PriceDroppedWithinDays)
+		filter["price_changed_at"] = bson.M{"$gte": since}
+		exprs = append(exprs, bson.M{"$lt": bson.A{"$price", "$previous_price"}})
+	}
+	if f.MinDaysOnMarket > 0 {
+		exprs = append(exprs, bson.M{"$gte": bson.A{daysOnMarketExpr(now), f.MinDaysOnMarket}})
+	}
+	if f.MaxDaysOnMarket > 0 {
+		exprs = append(exprs, bson.M{"$lte": bson.A{daysOnMarketExpr(now), f.MaxDaysOnMarket}})
+	}
+	if len(exprs) > 0 {
+		filter["$expr"] = bson.M{"$and": exprs}
+	}
+
+	return filter
+}
+
+// listingSort returns the sort order of a filter, newest scrapes first by
+// default. _id breaks ties so that pages do not overlap.
+func listingSort(f *ListingFilter) bson.D {
+	if f == nil || f.SortBy == "" || !slices.Contains(ListingSortFields, f.SortBy) {
+		return bson.D{{Key: "scraped_at", Value: -1}, {Key: "_id", Value: -1}}
+	}
+
+	dir := 1
+	if f.SortDesc {
+		dir = -1
+	}
+	return bson.D{{Key: f.SortBy, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// daysOnMarketExpr computes whole days between created_at and delisted_at,
+// or now for listings that are still active, matching Listing.ComputeDaysOnMarket
+func daysOnMarketExpr(now time.Time) bson.M {
+	return bson.M{"$floor": bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$delisted_at", now}}, "$created_at"}},
+		int64(24 * time.Hour / time.Millisecond),
+	}}}
+}
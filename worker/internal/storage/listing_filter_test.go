@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestListingQueryQuotesLocation(t *testing.T) {
+	q := listingQuery(&ListingFilter{Location: "(a+)+ Jakarta.Selatan"}, time.Now())
+
+	loc, ok := q["location"].(bson.M)
+	if !ok {
+		t.Fatalf("expected a location filter, got %v", q["location"])
+	}
+	if got := loc["$regex"]; got != `\(a\+\)\+ Jakarta\.Selatan` {
+		t.Fatalf("expected a quoted pattern, got %v", got)
+	}
+}
+
+func TestListingQueryPriceSkipsUnknownPrices(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter ListingFilter
+		want   any
+	}{
+		{name: "no price filter", filter: ListingFilter{}, want: nil},
+		{name: "max price", filter: ListingFilter{MaxPrice: 500}, want: bson.M{"$gt": 0, "$lte": 500.0}},
+		{name: "min price", filter: ListingFilter{MinPrice: 100}, want: bson.M{"$gt": 0, "$gte": 100.0}},
+		{name: "sort by price", filter: ListingFilter{SortBy: "price"}, want: bson.M{"$gt": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := listingQuery(&tt.filter, time.Now())
+			got, ok := q["price"]
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("expected no price filter, got %v", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
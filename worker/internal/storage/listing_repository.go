@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
@@ -13,12 +14,14 @@ import (
 )
 
 type mongoListingRepository struct {
-	collection *mongo.Collection
+	collection   *mongo.Collection
+	priceChanges *mongo.Collection
 }
 
 // NewListingRepository creates a new listing repository
 func NewListingRepository(db *mongo.Database) ListingRepository {
 	collection := db.Collection("listings")
+	priceChanges := db.Collection("price_changes")
 
 	// Create indexes in background
 	go func() {
@@ -41,56 +44,91 @@ func NewListingRepository(db *mongo.Database) ListingRepository {
 			Keys: bson.M{"price": 1},
 		}
 
+		// Listing type and price index for price queries, which never mix
+		// sale and rent listings
+		typePriceIndex := mongo.IndexModel{
+			Keys: bson.D{{Key: "listing_type", Value: 1}, {Key: "rent_period", Value: 1}, {Key: "price", Value: 1}},
+		}
+
+		// Property type index for filtering
+		propertyTypeIndex := mongo.IndexModel{
+			Keys: bson.M{"property_type": 1},
+		}
+
+		// Address indexes for region filters
+		addressIndexes := []mongo.IndexModel{
+			{Keys: bson.M{"address.province_code": 1}},
+			{Keys: bson.M{"address.city_code": 1}},
+			{Keys: bson.M{"address.district_code": 1}},
+			{Keys: bson.M{"address.village_code": 1}},
+		}
+
 		// Scraped at index for sorting
 		scrapedIndex := mongo.IndexModel{
 			Keys: bson.M{"scraped_at": -1},
 		}
 
-		collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// Price changed at index for "price dropped" queries
+		priceChangedIndex := mongo.IndexModel{
+			Keys: bson.M{"price_changed_at": -1},
+		}
+
+		// Site and status index for lifecycle updates
+		statusIndex := mongo.IndexModel{
+			Keys: bson.D{{Key: "site_name", Value: 1}, {Key: "status", Value: 1}},
+		}
+
+		collection.Indexes().CreateMany(ctx, append([]mongo.IndexModel{
 			urlIndex,
 			siteIndex,
 			priceIndex,
+			typePriceIndex,
+			propertyTypeIndex,
 			scrapedIndex,
+			priceChangedIndex,
+			statusIndex,
+		}, addressIndexes...))
+
+		// Price history is read per listing, newest first
+		priceChanges.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "listing_id", Value: 1}, {Key: "changed_at", Value: -1}},
 		})
 	}()
 
 	return &mongoListingRepository{
-		collection: collection,
+		collection:   collection,
+		priceChanges: priceChanges,
 	}
 }
 
 func (r *mongoListingRepository) Save(ctx context.Context, listing *model.Listing) error {
-	now := time.Now()
-
-	// Check if listing exists
-	existing, err := r.FindByURL(ctx, listing.URL)
+	result, err := r.SaveMany(ctx, []*model.Listing{listing})
 	if err != nil {
 		return err
 	}
-
-	if existing != nil {
-		// Update existing listing
-		listing.CreatedAt = existing.CreatedAt
-		listing.UpdatedAt = now
-		listing.ScrapedAt = now
-	} else {
-		// New listing
-		listing.CreatedAt = now
-		listing.UpdatedAt = now
-		listing.ScrapedAt = now
+	if err := result.Errors[0]; err != nil {
+		return fmt.Errorf("saving listing: %w", err)
 	}
+	return nil
+}
 
-	// Upsert based on URL
-	filter := bson.M{"url": listing.URL}
-	update := bson.M{"$set": listing}
+func (r *mongoListingRepository) FindByID(ctx context.Context, id string) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		// Not an ID this repository could have issued
+		return nil, nil
+	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err = r.collection.UpdateOne(ctx, filter, update, opts)
+	var listing model.Listing
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&listing)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("saving listing: %w", err)
+		return nil, fmt.Errorf("finding listing: %w", err)
 	}
 
-	return nil
+	return &listing, nil
 }
 
 func (r *mongoListingRepository) FindByURL(ctx context.Context, url string) (*model.Listing, error) {
@@ -109,35 +147,10 @@ func (r *mongoListingRepository) FindByURL(ctx context.Context, url string) (*mo
 }
 
 func (r *mongoListingRepository) FindAll(ctx context.Context, f *ListingFilter) ([]*model.Listing, error) {
-	filter := bson.M{}
-
-	if f != nil {
-		if f.SiteName != "" {
-			filter["site_name"] = f.SiteName
-		}
-		if f.MinPrice > 0 || f.MaxPrice > 0 {
-			priceFilter := bson.M{}
-			if f.MinPrice > 0 {
-				priceFilter["$gte"] = f.MinPrice
-			}
-			if f.MaxPrice > 0 {
-				priceFilter["$lte"] = f.MaxPrice
-			}
-			filter["price"] = priceFilter
-		}
-		if f.Location != "" {
-			filter["location"] = bson.M{"$regex": f.Location, "$options": "i"}
-		}
-		if f.MinBedrooms > 0 {
-			filter["bedrooms"] = bson.M{"$gte": f.MinBedrooms}
-		}
-		if f.MinBathrooms > 0 {
-			filter["bathrooms"] = bson.M{"$gte": f.MinBathrooms}
-		}
-	}
+	filter := listingQuery(f, time.Now())
 
 	opts := options.Find().
-		SetSort(bson.M{"scraped_at": -1})
+		SetSort(listingSort(f))
 
 	if f != nil {
 		if f.Limit > 0 {
@@ -159,34 +172,108 @@ func (r *mongoListingRepository) FindAll(ctx context.Context, f *ListingFilter)
 		return nil, fmt.Errorf("decoding listings: %w", err)
 	}
 
+	now := time.Now()
+	for _, l := range listings {
+		l.ComputeDaysOnMarket(now)
+	}
+
 	return listings, nil
 }
 
+// MarkUnseen counts a missed run for every active listing of a site that the
+// given run did not see, and delists those that reached threshold missed runs.
+// It must only be called after a full crawl of the site. It returns the number
+// of listings delisted.
+func (r *mongoListingRepository) MarkUnseen(ctx context.Context, siteName, runID string, threshold int) (int64, error) {
+	active := bson.M{"$ne": model.ListingStatusInactive}
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"site_name": siteName, "status": active, "run_id": bson.M{"$ne": runID}},
+		bson.M{"$inc": bson.M{"missed_runs": 1}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("counting missed runs: %w", err)
+	}
+
+	now := time.Now()
+	res, err := r.collection.UpdateMany(ctx,
+		bson.M{"site_name": siteName, "status": active, "missed_runs": bson.M{"$gte": threshold}},
+		bson.M{"$set": bson.M{
+			"status":      model.ListingStatusInactive,
+			"delisted_at": now,
+			"updated_at":  now,
+		}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("delisting listings: %w", err)
+	}
+
+	return res.ModifiedCount, nil
+}
+
 func (r *mongoListingRepository) UpdatePrice(ctx context.Context, url string, newPrice float64) error {
+	existing, err := r.FindByURL(ctx, url)
+	if err != nil {
+		return err
+	}
+	if existing == nil || existing.Price == newPrice {
+		return nil
+	}
+
+	now := time.Now()
 	filter := bson.M{"url": url}
 	update := bson.M{
 		"$set": bson.M{
-			"price":      newPrice,
-			"updated_at": time.Now(),
+			"price":            newPrice,
+			"previous_price":   existing.Price,
+			"price_changed_at": now,
+			"updated_at":       now,
 		},
 	}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
+	_, err = r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("updating price: %w", err)
 	}
 
-	return nil
+	return r.recordPriceChange(ctx, &model.PriceChange{
+		ListingID: existing.ID,
+		URL:       url,
+		SiteName:  existing.SiteName,
+		OldPrice:  existing.Price,
+		NewPrice:  newPrice,
+		ChangedAt: now,
+	})
 }
 
-func (r *mongoListingRepository) Count(ctx context.Context, f *ListingFilter) (int64, error) {
-	filter := bson.M{}
+func (r *mongoListingRepository) FindPriceHistory(ctx context.Context, listingID string) ([]*model.PriceChange, error) {
+	opts := options.Find().
+		SetSort(bson.M{"changed_at": -1})
 
-	if f != nil {
-		if f.SiteName != "" {
-			filter["site_name"] = f.SiteName
-		}
+	cursor, err := r.priceChanges.Find(ctx, bson.M{"listing_id": listingID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("finding price history: %w", err)
 	}
+	defer cursor.Close(ctx)
+
+	changes := make([]*model.PriceChange, 0)
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, fmt.Errorf("decoding price history: %w", err)
+	}
+
+	return changes, nil
+}
+
+// recordPriceChange appends an entry to the price history collection
+func (r *mongoListingRepository) recordPriceChange(ctx context.Context, change *model.PriceChange) error {
+	if _, err := r.priceChanges.InsertOne(ctx, change); err != nil {
+		return fmt.Errorf("recording price change: %w", err)
+	}
+	return nil
+}
+
+func (r *mongoListingRepository) Count(ctx context.Context, f *ListingFilter) (int64, error) {
+	filter := listingQuery(f, time.Now())
 
 	count, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
@@ -195,3 +282,71 @@ func (r *mongoListingRepository) Count(ctx context.Context, f *ListingFilter) (i
 
 	return count, nil
 }
+
+// backfillBatchSize is the number of address updates sent in one bulk write
+const backfillBatchSize = 500
+
+// BackfillAddresses resolves the address of stored listings that have a
+// location but no address: listings saved before addresses were resolved,
+// or whose region was missing from the gazetteer at the time.
+func (r *mongoListingRepository) BackfillAddresses(ctx context.Context, resolve func(location string) *model.Address) (*BackfillResult, error) {
+	filter := bson.M{
+		"address":  bson.M{"$exists": false},
+		"location": bson.M{"$nin": bson.A{"", nil}},
+	}
+	opts := options.Find().SetProjection(bson.M{"url": 1, "location": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("finding listings without address: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	result := &BackfillResult{}
+	writes := make([]mongo.WriteModel, 0, backfillBatchSize)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		res, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return fmt.Errorf("backfilling addresses: %w", err)
+		}
+		result.Updated += res.ModifiedCount
+		writes = writes[:0]
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			URL      string `bson:"url"`
+			Location string `bson:"location"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return result, fmt.Errorf("decoding listing: %w", err)
+		}
+
+		addr := resolve(doc.Location)
+		if addr == nil {
+			result.Unresolved++
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"url": doc.URL, "address": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"address": addr}}))
+
+		if len(writes) >= backfillBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return result, fmt.Errorf("reading listings without address: %w", err)
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 
 	"github.com/Alwanly/Houses-Prices/worker/internal/model"
 )
@@ -9,15 +10,43 @@ import (
 // ListingRepository defines operations for listing storage
 type ListingRepository interface {
 	Save(ctx context.Context, listing *model.Listing) error
+	SaveMany(ctx context.Context, listings []*model.Listing) (*SaveResult, error)
+	FindByID(ctx context.Context, id string) (*model.Listing, error)
 	FindByURL(ctx context.Context, url string) (*model.Listing, error)
+	FindByURLs(ctx context.Context, urls []string) (map[string]*model.Listing, error)
 	FindAll(ctx context.Context, filter *ListingFilter) ([]*model.Listing, error)
 	UpdatePrice(ctx context.Context, url string, newPrice float64) error
 	Count(ctx context.Context, filter *ListingFilter) (int64, error)
+	FindPriceHistory(ctx context.Context, listingID string) ([]*model.PriceChange, error)
+	MarkUnseen(ctx context.Context, siteName, runID string, threshold int) (int64, error)
+	BackfillAddresses(ctx context.Context, resolve func(location string) *model.Address) (*BackfillResult, error)
+}
+
+// BackfillResult reports how many stored listings got an address and how
+// many locations still resolve to none
+type BackfillResult struct {
+	Updated    int64
+	Unresolved int64
 }
 
 // ListingFilter defines filter options for querying listings
 type ListingFilter struct {
-	SiteName     string
+	SiteName string
+
+	// ListingType keeps sale or rent listings, RentPeriod rent listings
+	// priced per that period. Listings stored without a type are for sale.
+	ListingType string
+	RentPeriod  string
+
+	// PropertyType keeps listings of one of model.PropertyTypes
+	PropertyType string
+
+	// Region codes of the listing address, matched exactly
+	ProvinceCode string
+	CityCode     string
+	DistrictCode string
+	VillageCode  string
+
 	MinPrice     float64
 	MaxPrice     float64
 	Location     string
@@ -25,4 +54,75 @@ type ListingFilter struct {
 	MinBathrooms int
 	Limit        int
 	Offset       int
+
+	// PriceDroppedWithinDays keeps listings whose price went down in the last N days
+	PriceDroppedWithinDays int
+
+	// Lifecycle filters; days on market bounds are inclusive, zero means unbounded
+	Status          string
+	MinDaysOnMarket int
+	MaxDaysOnMarket int
+
+	// SortBy is one of ListingSortFields; empty sorts by scraped_at, newest first
+	SortBy   string
+	SortDesc bool
+}
+
+// RunRepository stores scrape run records
+type RunRepository interface {
+	Save(ctx context.Context, run *model.JobStatus) error
+	FindByID(ctx context.Context, id string) (*model.JobStatus, error)
+	FindAll(ctx context.Context, filter *RunFilter) ([]*model.JobStatus, error)
+	FindLastBySite(ctx context.Context, siteName string) (*model.JobStatus, error)
+	Count(ctx context.Context, filter *RunFilter) (int64, error)
+}
+
+// RunFilter defines filter options for querying scrape runs, newest first
+type RunFilter struct {
+	SiteName string
+	Status   string
+	Limit    int
+	Offset   int
+}
+
+// SaveOutcome describes what a save did to a single listing
+type SaveOutcome string
+
+// Save outcomes
+const (
+	SaveInserted  SaveOutcome = "inserted"
+	SaveUpdated   SaveOutcome = "updated"
+	SaveUnchanged SaveOutcome = "unchanged"
+	SaveFailed    SaveOutcome = "failed"
+
+	// SaveStale marks a listing rejected because a newer lease holder
+	// already wrote it; see ErrStaleFence
+	SaveStale SaveOutcome = "stale"
+
+	// SaveDuplicate marks a listing skipped because a later copy of the same
+	// URL in the batch was saved instead
+	SaveDuplicate SaveOutcome = "duplicate"
+)
+
+// ErrStaleFence rejects a write made under an older fencing token than the
+// one the stored listing was written with, i.e. by a worker that lost its lock
+var ErrStaleFence = errors.New("write fenced off by a newer lock holder")
+
+// SaveResult reports the outcome of a bulk save. Outcomes and Errors are
+// aligned with the listings passed in; Errors holds nil for listings that
+// were saved.
+type SaveResult struct {
+	Inserted     int
+	Updated      int
+	Unchanged    int
+	Failed       int
+	Stale        int
+	PriceChanged int
+	Outcomes     []SaveOutcome
+	Errors       []error
+}
+
+// Saved returns the number of listings written successfully
+func (r *SaveResult) Saved() int {
+	return r.Inserted + r.Updated + r.Unchanged
 }
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Alwanly/Houses-Prices/worker/internal/model"
+)
+
+type mongoRunRepository struct {
+	collection *mongo.Collection
+}
+
+// NewRunRepository creates a new scrape run repository
+func NewRunRepository(db *mongo.Database) RunRepository {
+	collection := db.Collection("scrape_runs")
+
+	// Create indexes in background
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			// Latest runs per site
+			{Keys: bson.D{{Key: "site_name", Value: 1}, {Key: "start_time", Value: -1}}},
+			// Latest runs across sites
+			{Keys: bson.M{"start_time": -1}},
+		})
+	}()
+
+	return &mongoRunRepository{collection: collection}
+}
+
+// Save inserts the run or replaces the stored record with the same job ID
+func (r *mongoRunRepository) Save(ctx context.Context, run *model.JobStatus) error {
+	_, err := r.collection.ReplaceOne(ctx,
+		bson.M{"_id": run.JobID},
+		run,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("saving run: %w", err)
+	}
+	return nil
+}
+
+func (r *mongoRunRepository) FindByID(ctx context.Context, id string) (*model.JobStatus, error) {
+	var run model.JobStatus
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding run: %w", err)
+	}
+	return &run, nil
+}
+
+func (r *mongoRunRepository) FindAll(ctx context.Context, f *RunFilter) ([]*model.JobStatus, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "start_time", Value: -1}, {Key: "_id", Value: -1}})
+
+	if f != nil {
+		if f.Limit > 0 {
+			opts.SetLimit(int64(f.Limit))
+		}
+		if f.Offset > 0 {
+			opts.SetSkip(int64(f.Offset))
+		}
+	}
+
+	cursor, err := r.collection.Find(ctx, runQuery(f), opts)
+	if err != nil {
+		return nil, fmt.Errorf("finding runs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var runs []*model.JobStatus
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("decoding runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *mongoRunRepository) FindLastBySite(ctx context.Context, siteName string) (*model.JobStatus, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "start_time", Value: -1}, {Key: "_id", Value: -1}})
+
+	var run model.JobStatus
+	err := r.collection.FindOne(ctx, bson.M{"site_name": siteName}, opts).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding last run: %w", err)
+	}
+	return &run, nil
+}
+
+func (r *mongoRunRepository) Count(ctx context.Context, f *RunFilter) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, runQuery(f))
+	if err != nil {
+		return 0, fmt.Errorf("counting runs: %w", err)
+	}
+	return count, nil
+}
+
+func runQuery(f *RunFilter) bson.M {
+	query := bson.M{}
+	if f == nil {
+		return query
+	}
+	if f.SiteName != "" {
+		query["site_name"] = f.SiteName
+	}
+	if f.Status != "" {
+		query["status"] = f.Status
+	}
+	return query
+}